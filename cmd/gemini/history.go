@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	"github.com/reugn/gemini-cli/internal/config"
+	"github.com/reugn/gemini-cli/internal/handler"
+	"github.com/reugn/gemini-cli/internal/history"
+	"github.com/reugn/gemini-cli/internal/terminal/color"
+	"github.com/spf13/cobra"
+)
+
+// newHistoryCommand returns the command operating on the chat history store.
+// The resume function starts a chat session restoring the stored record with
+// the ID.
+func newHistoryCommand(configPath, historyDir *string, resume func(id string) error) *cobra.Command {
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "Chat history store operations",
+	}
+
+	searchCmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Search the stored conversations",
+		Long: "Search the stored conversations\n\n" +
+			"Lists the stored conversations containing all of the query terms, ranked by\n" +
+			"relevance, along with the matching snippets. If run in a terminal, the selected\n" +
+			"conversation is resumed in an interactive chat session.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			configuration, err := config.NewConfiguration(*configPath)
+			if err != nil {
+				return err
+			}
+			store, err := openHistoryStore(configuration, *configPath, *historyDir)
+			if err != nil {
+				return err
+			}
+
+			query := strings.Join(args, " ")
+			matches, err := store.Search(query)
+			if err != nil {
+				return err
+			}
+			if len(matches) == 0 {
+				fmt.Printf("No stored history records match %q.\n", query)
+				return nil
+			}
+
+			if !isTerminal(os.Stdout) {
+				fmt.Print(handler.FormatMatches(matches, func(term string) string { return term }))
+				return nil
+			}
+			fmt.Print(handler.FormatMatches(matches, color.Cyan))
+			if !isTerminal(os.Stdin) {
+				return nil
+			}
+
+			match, err := handler.SelectMatch(matches, "Select conversation history to resume")
+			if err != nil {
+				if errors.Is(err, promptui.ErrInterrupt) || errors.Is(err, promptui.ErrEOF) {
+					return nil
+				}
+				return err
+			}
+			return resume(match.ID)
+		},
+	}
+	historyCmd.AddCommand(searchCmd)
+
+	return historyCmd
+}
+
+// openHistoryStore returns the chat history store located in the directory,
+// or next to the configuration file by default. The history records kept in
+// the configuration file by the earlier versions of the application are
+// migrated to the store.
+func openHistoryStore(configuration *config.Configuration, configPath,
+	historyDir string) (*history.Store, error) {
+	if historyDir == "" {
+		historyDir = filepath.Join(filepath.Dir(configPath), defaultHistoryDir)
+	}
+
+	store := history.NewStore(historyDir)
+	if err := migrateHistory(store, configuration); err != nil {
+		return nil, fmt.Errorf("failed to migrate chat history: %w", err)
+	}
+	return store, nil
+}
+
+// migrateHistory moves the chat history records kept in the configuration
+// file by the earlier versions of the application to the history store.
+func migrateHistory(store *history.Store, configuration *config.Configuration) error {
+	if len(configuration.Data.History) == 0 {
+		return nil
+	}
+
+	if err := store.Import(configuration.Data.History); err != nil {
+		return err
+	}
+	return configuration.Update(func(data *config.ApplicationData) {
+		data.History = nil
+	})
+}
+
+// isTerminal returns true if the file is a terminal.
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
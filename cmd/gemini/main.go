@@ -3,33 +3,72 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"os/user"
+	"slices"
+	"strings"
 
 	"github.com/reugn/gemini-cli/gemini"
 	"github.com/reugn/gemini-cli/internal/chat"
 	"github.com/reugn/gemini-cli/internal/config"
+	"github.com/reugn/gemini-cli/internal/handler"
+	"github.com/reugn/gemini-cli/internal/history"
+	"github.com/reugn/gemini-cli/internal/mcp"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 const (
 	version           = "0.5.0"
 	defaultConfigPath = "gemini_cli_config.json"
+	defaultHistoryDir = "gemini_cli_history"
 )
 
+var mcpClient = mcp.Implementation{
+	Name:    "gemini-cli",
+	Version: version,
+}
+
 func run() int {
 	rootCmd := &cobra.Command{
-		Short:   "Gemini CLI Tool",
+		Use:   "gemini [prompt]",
+		Short: "Gemini CLI Tool",
+		Long: "Gemini CLI Tool\n\n" +
+			"Starts an interactive chat session, unless a prompt is given as arguments\n" +
+			"or piped to the standard input, in which case a single query is sent\n" +
+			"and the response is written to the standard output.",
 		Version: version,
 	}
 
 	var (
-		opts       chat.Opts
-		configPath string
+		opts            chat.Opts
+		configPath      string
+		historyDir      string
+		continueSession bool
+		resumeLabel     string
+		providerFlags   config.Provider
 	)
 
 	rootCmd.Flags().StringVarP(&opts.GenerativeModel, "model", "m", gemini.DefaultModel,
 		"generative model name")
+	rootCmd.Flags().StringVar(&providerFlags.Type, "provider", "",
+		"generative model provider (gemini, openai, ollama), overriding the configured one")
+	rootCmd.Flags().StringVar(&providerFlags.Backend, "backend", "",
+		"gemini provider backend (gemini_api, vertex_ai)")
+	rootCmd.Flags().StringVar(&providerFlags.Project, "project", "",
+		"Google Cloud project of the Vertex AI backend")
+	rootCmd.Flags().StringVar(&providerFlags.Location, "location", "",
+		"Google Cloud location of the Vertex AI backend")
+	rootCmd.Flags().StringVar(&providerFlags.BaseURL, "base-url", "",
+		"base URL of the provider API")
+	rootCmd.Flags().StringVar(&providerFlags.APIVersion, "api-version", "",
+		"API version of the gemini provider")
+	rootCmd.Flags().StringVar(&providerFlags.APIKeyFile, "api-key-file", "",
+		"path to the file containing the API key")
+	rootCmd.Flags().StringVarP(&opts.SystemPrompt, "prompt", "p", "",
+		"system prompt label from the configuration file")
 	rootCmd.Flags().BoolVar(&opts.Multiline, "multiline", false,
 		"read input as a multi-line string")
 	rootCmd.Flags().StringVarP(&opts.LineTerminator, "term", "t", "$",
@@ -38,21 +77,137 @@ func run() int {
 		"markdown format style (ascii, dark, light, pink, notty, dracula, tokyo-night)")
 	rootCmd.Flags().IntVarP(&opts.WordWrap, "wrap", "w", 80,
 		"line length for response word wrapping")
-	rootCmd.Flags().StringVarP(&configPath, "config", "c", defaultConfigPath,
+	rootCmd.Flags().BoolVar(&opts.Stream, "stream", false,
+		"render the model response incrementally as it is generated")
+	rootCmd.Flags().BoolVar(&opts.Raw, "raw", false,
+		"output the model response as raw markdown")
+	rootCmd.Flags().StringVar(&opts.Thoughts, "thoughts", string(handler.ThoughtsCollapsed),
+		"model thoughts display mode (hidden, collapsed, expanded)")
+	rootCmd.Flags().BoolVar(&opts.Citations, "citations", false,
+		"insert citation markers into the grounded responses")
+	rootCmd.Flags().BoolVar(&opts.Usage, "usage", false,
+		"show the token usage and latency after each response")
+	rootCmd.Flags().BoolVar(&opts.JSON, "json", false,
+		"output the model response as raw JSON")
+	rootCmd.Flags().StringVar(&opts.Schema, "schema", "",
+		"response JSON schema name from the configuration file or schema file path (implies --json)")
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", defaultConfigPath,
 		"path to configuration file in JSON format")
+	rootCmd.PersistentFlags().StringVar(&historyDir, "history-dir", "",
+		"path to chat history directory (default \""+defaultHistoryDir+"\" next to the configuration file)")
+	rootCmd.Flags().BoolVar(&continueSession, "continue", false,
+		"continue the most recent autosaved chat session")
+	rootCmd.Flags().StringVar(&resumeLabel, "resume", "",
+		"resume the stored chat history record with the label or ID")
+	rootCmd.MarkFlagsMutuallyExclusive("continue", "resume")
+	rootCmd.Flags().Float32(generationFlagName(gemini.ParamTemperature), 0,
+		"degree of randomness in token selection")
+	rootCmd.Flags().Float32(generationFlagName(gemini.ParamTopP), 0,
+		"cumulative probability threshold for token selection")
+	rootCmd.Flags().Float32(generationFlagName(gemini.ParamTopK), 0,
+		"number of the most probable tokens considered for selection")
+	rootCmd.Flags().Int32(generationFlagName(gemini.ParamMaxOutputTokens), 0,
+		"maximum number of tokens in the response")
+	rootCmd.Flags().Int32(generationFlagName(gemini.ParamCandidateCount), 0,
+		"number of response variations to return")
+	rootCmd.Flags().String(generationFlagName(gemini.ParamStopSequences), "",
+		"comma-separated character sequences that stop the generation")
+	rootCmd.Flags().Int32(generationFlagName(gemini.ParamSeed), 0,
+		"seed used in decoding for reproducible results")
+	rootCmd.Flags().Int32(generationFlagName(gemini.ParamThinkingBudget), 0,
+		"number of thinking tokens (0 disables thinking, -1 enables dynamic thinking)")
+	rootCmd.Flags().Bool(generationFlagName(gemini.ParamIncludeThoughts), false,
+		"include the model thought summaries in the response")
+
+	rootCmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
+		opts.GenerationConfig, err = generationConfigFlags(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(handler.ThoughtsModes, handler.ThoughtsMode(opts.Thoughts)) {
+			return fmt.Errorf("invalid thoughts display mode: %s", opts.Thoughts)
+		}
+		if providerFlags.Type != "" && !slices.Contains(config.ProviderTypes, providerFlags.Type) {
+			return fmt.Errorf("unsupported provider: %s", providerFlags.Type)
+		}
+
+		// the arguments are valid at this point; do not print usage on runtime errors
+		cmd.SilenceUsage = true
 
-	rootCmd.RunE = func(_ *cobra.Command, _ []string) (err error) {
 		configuration, err := config.NewConfiguration(configPath)
 		if err != nil {
 			return err
 		}
 
-		chatSession, err := gemini.NewChatSession(context.Background(), opts.GenerativeModel,
-			configuration.Data.GenaiContentConfig())
+		opts.HistoryStore, err = openHistoryStore(configuration, configPath, historyDir)
+		if err != nil {
+			return err
+		}
+
+		providerConfig := configuration.Data.Provider.Override(providerFlags)
+		if !cmd.Flags().Changed("model") {
+			opts.GenerativeModel, err = defaultModel(&providerConfig)
+			if err != nil {
+				return err
+			}
+		}
+
+		query, err := readQuery(args)
 		if err != nil {
 			return err
 		}
 
+		// a single query is autosaved only if it continues a restored session
+		var restored *history.Session
+		if query == "" || continueSession || resumeLabel != "" {
+			opts.Journal, restored, err = openJournal(opts.HistoryStore, continueSession, resumeLabel)
+			if err != nil {
+				return err
+			}
+		}
+		if restored != nil {
+			applySessionOptions(&opts, configuration.Data, restored, cmd.Flags())
+		}
+
+		if opts.Schema != "" {
+			opts.ResponseSchema, err = configuration.Data.ResponseSchema(opts.Schema)
+			if err != nil {
+				return err
+			}
+			opts.JSON = true
+		}
+
+		if !opts.JSON {
+			// the tools are not available in the JSON output mode
+			opts.MCPServers = mcp.Connect(context.Background(), mcpClient,
+				configuration.Data.MCPServers)
+			defer func() { err = errors.Join(err, opts.MCPServers.Close()) }()
+			if err := opts.MCPServers.Errors(); err != nil {
+				// the failed servers are reported, but do not prevent the chat
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+
+		provider, err := newProvider(context.Background(), &providerConfig)
+		if err != nil {
+			return err
+		}
+
+		chatSession, err := newChatSession(provider, configuration, &opts)
+		if err != nil {
+			return err
+		}
+		if restored != nil {
+			if err := restoreSession(chatSession, restored, &opts, cmd.Flags()); err != nil {
+				return err
+			}
+		}
+
+		if query != "" {
+			// run in non-interactive mode
+			return chat.Query(chatSession, configuration, query, &opts)
+		}
+
 		chatHandler, err := chat.New(getCurrentUser(), chatSession, configuration, &opts)
 		if err != nil {
 			return err
@@ -63,6 +218,12 @@ func run() int {
 		return nil
 	}
 
+	addSubcommands(rootCmd, newHistoryCommand(&configPath, &historyDir, func(id string) error {
+		resumeLabel = id
+		return rootCmd.RunE(rootCmd, nil)
+	}))
+	dispatchSubcommand(rootCmd, os.Args[1:])
+
 	if err := rootCmd.Execute(); err != nil {
 		return 1
 	}
@@ -70,6 +231,141 @@ func run() int {
 	return 0
 }
 
+// addSubcommands adds the subcommands to the root command. As the prompt is
+// given as arguments, the default help and completion commands are disabled.
+func addSubcommands(rootCmd *cobra.Command, subcommands ...*cobra.Command) {
+	rootCmd.Args = cobra.ArbitraryArgs
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.SetHelpCommand(&cobra.Command{Hidden: true})
+	rootCmd.AddCommand(subcommands...)
+}
+
+// dispatchSubcommand removes the subcommands of the root command if the
+// arguments start with a subcommand name, but do not form a valid invocation
+// of the subcommand, so that prompts like "history of rome" are sent to the
+// model instead.
+func dispatchSubcommand(rootCmd *cobra.Command, args []string) {
+	cmd, cmdArgs, err := rootCmd.Find(args)
+	if err != nil || cmd == rootCmd {
+		return
+	}
+
+	cmd.InitDefaultHelpFlag()
+	if err := cmd.ParseFlags(cmdArgs); err == nil {
+		if help, _ := cmd.Flags().GetBool("help"); help {
+			return
+		}
+		if cmd.Runnable() && cmd.ValidateArgs(cmd.Flags().Args()) == nil {
+			return
+		}
+	}
+	rootCmd.ResetCommands()
+}
+
+// newChatSession returns a new chat session using the provider, configured
+// using the application data and the command line options.
+func newChatSession(provider gemini.Provider, configuration *config.Configuration,
+	opts *chat.Opts) (*gemini.ChatSession, error) {
+	contentConfig := configuration.Data.GenaiContentConfig()
+	if opts.SystemPrompt != "" {
+		systemPrompt, ok := configuration.Data.SystemPrompts[opts.SystemPrompt]
+		if !ok {
+			return nil, fmt.Errorf("system prompt %q not found", opts.SystemPrompt)
+		}
+		contentConfig.SystemInstruction = systemPrompt.ToContent()
+	}
+
+	configuration.Data.SystemPromptGenerationConfig(opts.SystemPrompt).
+		Merge(opts.GenerationConfig).
+		Apply(contentConfig)
+
+	if opts.JSON {
+		// the JSON output cannot be combined with the tools, so the built-in
+		// tools and the functions are not used in this mode
+		contentConfig.Tools = nil
+		contentConfig.ResponseMIMEType = "application/json"
+		if opts.ResponseSchema != nil {
+			contentConfig.ResponseJsonSchema = opts.ResponseSchema
+		}
+	}
+
+	chatSession := gemini.NewChatSession(context.Background(), provider, opts.GenerativeModel,
+		contentConfig)
+
+	if !opts.JSON {
+		functions, err := configuration.Data.Functions()
+		if err != nil {
+			return nil, err
+		}
+		functions = append(functions, opts.MCPServers.Functions()...)
+		if len(functions) > 0 {
+			if err := chatSession.RegisterFunctions(functions...); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := chatSession.SetMaxFunctionCalls(configuration.Data.MaxFunctionCalls); err != nil {
+		return nil, err
+	}
+	chatSession.SetRetryPolicy(configuration.Data.Retry.RetryPolicy())
+
+	return chatSession, nil
+}
+
+// generationConfigFlags returns the generation parameters set using the
+// command line flags.
+func generationConfigFlags(flags *pflag.FlagSet) (*gemini.GenerationConfig, error) {
+	generationConfig := &gemini.GenerationConfig{}
+	for _, parameter := range gemini.GenerationParameters {
+		flag := flags.Lookup(generationFlagName(parameter))
+		if flag == nil || !flag.Changed {
+			continue
+		}
+		if err := generationConfig.Set(parameter, flag.Value.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	return generationConfig, nil
+}
+
+// generationFlagName returns the command line flag name for the generation
+// parameter (e.g., top-p for top_p).
+func generationFlagName(parameter string) string {
+	return strings.ReplaceAll(parameter, "_", "-")
+}
+
+// readQuery returns the query for the non-interactive mode, composed of the
+// command line arguments and the piped standard input. An empty string is
+// returned if neither is provided.
+func readQuery(args []string) (string, error) {
+	query := strings.Join(args, " ")
+
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return "", fmt.Errorf("error stating stdin: %w", err)
+	}
+	if info.Mode()&os.ModeCharDevice != 0 {
+		// stdin is a terminal
+		return query, nil
+	}
+
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("error reading stdin: %w", err)
+	}
+
+	piped := strings.TrimSpace(string(input))
+	switch {
+	case piped == "":
+		return query, nil
+	case query == "":
+		return piped, nil
+	default:
+		return query + "\n\n" + piped, nil
+	}
+}
+
 func getCurrentUser() string {
 	currentUser, err := user.Current()
 	if err != nil {
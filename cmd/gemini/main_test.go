@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestDispatchSubcommand(t *testing.T) {
+	tests := []struct {
+		args    string
+		command string
+	}{
+		{"", "gemini"},
+		{"what is the capital of Italy", "gemini"},
+		{"history of rome", "gemini"},
+		{"history search", "gemini"},
+		{"help me write a poem", "gemini"},
+		{"completion of the task", "gemini"},
+		{"-m gemini-2.5-pro history of rome", "gemini"},
+		{"history search kafka retry", "search"},
+		{"--history-dir /tmp history search kafka", "search"},
+		{"history --help", "history"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			rootCmd := newTestRootCommand()
+			args := strings.Fields(tt.args)
+			dispatchSubcommand(rootCmd, args)
+			// the help command is added on execution
+			rootCmd.InitDefaultHelpCmd()
+
+			cmd, _, err := rootCmd.Find(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cmd.Name() != tt.command {
+				t.Errorf("command = %q, want %q", cmd.Name(), tt.command)
+			}
+		})
+	}
+}
+
+func newTestRootCommand() *cobra.Command {
+	var configPath, historyDir, model string
+	rootCmd := &cobra.Command{
+		Use:  "gemini",
+		RunE: func(*cobra.Command, []string) error { return nil },
+	}
+	rootCmd.Flags().StringVarP(&model, "model", "m", "", "generative model name")
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "path to configuration file")
+	rootCmd.PersistentFlags().StringVar(&historyDir, "history-dir", "", "chat history directory")
+	addSubcommands(rootCmd, newHistoryCommand(&configPath, &historyDir, func(string) error { return nil }))
+	return rootCmd
+}
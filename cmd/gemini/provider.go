@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"cloud.google.com/go/auth/credentials"
+	"github.com/reugn/gemini-cli/gemini"
+	"github.com/reugn/gemini-cli/internal/config"
+	"github.com/reugn/gemini-cli/internal/openai"
+	"google.golang.org/genai"
+)
+
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// newProvider returns the generative model provider of the configured type.
+func newProvider(ctx context.Context, providerConfig *config.Provider) (gemini.Provider, error) {
+	apiKey, err := providerConfig.APIKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch providerConfig.Type {
+	case config.ProviderGemini, "":
+		clientConfig, err := genaiClientConfig(providerConfig, apiKey)
+		if err != nil {
+			return nil, err
+		}
+		return gemini.NewGeminiProvider(ctx, clientConfig)
+	case config.ProviderOpenAI:
+		if apiKey == "" {
+			apiKey = os.Getenv("OPENAI_API_KEY")
+		}
+		return openai.NewProvider(valueOrDefault(providerConfig.BaseURL, openai.DefaultBaseURL), apiKey), nil
+	case config.ProviderOllama:
+		return openai.NewProvider(valueOrDefault(providerConfig.BaseURL, openai.OllamaBaseURL), apiKey), nil
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", providerConfig.Type)
+	}
+}
+
+// genaiClientConfig returns the gemini provider client configuration.
+// The unset options fall back to the environment variables.
+func genaiClientConfig(providerConfig *config.Provider, apiKey string) (*genai.ClientConfig, error) {
+	clientConfig := &genai.ClientConfig{
+		APIKey:   apiKey,
+		Project:  providerConfig.Project,
+		Location: providerConfig.Location,
+		HTTPOptions: genai.HTTPOptions{
+			BaseURL:    providerConfig.BaseURL,
+			APIVersion: providerConfig.APIVersion,
+		},
+	}
+
+	switch providerConfig.Backend {
+	case "":
+	case config.BackendGeminiAPI:
+		clientConfig.Backend = genai.BackendGeminiAPI
+	case config.BackendVertexAI:
+		clientConfig.Backend = genai.BackendVertexAI
+	default:
+		return nil, fmt.Errorf("unsupported backend: %s", providerConfig.Backend)
+	}
+
+	if providerConfig.CredentialsFile != "" {
+		creds, err := credentials.DetectDefault(&credentials.DetectOptions{
+			Scopes:          []string{cloudPlatformScope},
+			CredentialsFile: providerConfig.CredentialsFile,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error loading credentials: %w", err)
+		}
+		clientConfig.Credentials = creds
+	}
+
+	return clientConfig, nil
+}
+
+// defaultModel returns the configured default generative model of the provider.
+// Only the gemini provider has a built-in default model.
+func defaultModel(providerConfig *config.Provider) (string, error) {
+	switch {
+	case providerConfig.Model != "":
+		return providerConfig.Model, nil
+	case providerConfig.Type == config.ProviderGemini || providerConfig.Type == "":
+		return gemini.DefaultModel, nil
+	default:
+		return "", fmt.Errorf("the generative model must be set for the %s provider", providerConfig.Type)
+	}
+}
+
+// valueOrDefault returns the value, or the default value if it is empty.
+func valueOrDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/reugn/gemini-cli/gemini"
+	"github.com/reugn/gemini-cli/internal/chat"
+	"github.com/reugn/gemini-cli/internal/config"
+	"github.com/reugn/gemini-cli/internal/history"
+	"github.com/spf13/pflag"
+)
+
+// openJournal returns the autosave journal of the chat session, along with
+// the session state to be restored, which is nil if a new session is started.
+// The most recent autosaved session is continued, or the stored history record
+// with the label or ID is resumed, if requested. If there is no autosaved
+// session to continue, a new session is started with a notice.
+func openJournal(store *history.Store, continueSession bool,
+	resumeLabel string) (*history.Journal, *history.Session, error) {
+	if continueSession {
+		journal, session, err := store.LatestJournal()
+		if !errors.Is(err, history.ErrNoJournal) {
+			return journal, session, err
+		}
+		fmt.Fprintln(os.Stderr, "No autosaved chat session to continue, starting a new session.")
+	}
+
+	var session *history.Session
+	if resumeLabel != "" {
+		conversation, err := loadConversation(store, resumeLabel)
+		if err != nil {
+			return nil, nil, err
+		}
+		session = conversation.Session()
+	}
+
+	journal, err := store.NewJournal()
+	if err != nil {
+		return nil, nil, err
+	}
+	return journal, session, nil
+}
+
+// loadConversation returns the stored conversation with the ID or the label.
+// If several conversations share the label, the most recently updated one
+// is returned.
+func loadConversation(store *history.Store, label string) (*history.Conversation, error) {
+	entries, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	// the entries are ordered by the update time
+	i := slices.IndexFunc(entries, func(e *history.Entry) bool { return e.ID == label })
+	if i < 0 {
+		i = slices.IndexFunc(entries, func(e *history.Entry) bool { return e.Label == label })
+	}
+	if i < 0 {
+		return nil, fmt.Errorf("chat history record %q not found", label)
+	}
+
+	return store.Load(entries[i].ID)
+}
+
+// applySessionOptions sets the model and the system prompt of the restored
+// session in the chat options, unless they are set using the command line
+// flags. The system prompt is looked up by the system instruction text, as
+// the configured prompts may have changed since the session was recorded.
+func applySessionOptions(opts *chat.Opts, data *config.ApplicationData,
+	session *history.Session, flags *pflag.FlagSet) {
+	if session.Model != "" && !flags.Changed("model") {
+		opts.GenerativeModel = session.Model
+	}
+	if !flags.Changed("prompt") {
+		opts.SystemPrompt = data.SystemPromptLabel(session.SystemInstruction)
+	}
+}
+
+// restoreSession restores the session state in the chat session, except for
+// the model, the system prompt and the generation parameters set using the
+// command line flags.
+func restoreSession(chatSession *gemini.ChatSession, session *history.Session,
+	opts *chat.Opts, flags *pflag.FlagSet) error {
+	restored := *session
+	if flags.Changed("model") {
+		restored.Model = chatSession.Model()
+	}
+	switch {
+	case flags.Changed("prompt"):
+		restored.SystemInstruction = chatSession.SystemInstruction()
+		restored.GenerationConfig = chatSession.GenerationConfig()
+	case restored.GenerationConfig != nil:
+		restored.GenerationConfig = restored.GenerationConfig.Merge(opts.GenerationConfig)
+	}
+
+	if err := restored.Restore(chatSession); err != nil {
+		return fmt.Errorf("failed to restore chat session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/reugn/gemini-cli/internal/history"
+	"google.golang.org/genai"
+)
+
+func TestOpenJournalContinue(t *testing.T) {
+	store := history.NewStore(t.TempDir())
+
+	// a new session is started if there is nothing to continue
+	journal, session, err := openJournal(store, true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if journal == nil || session != nil {
+		t.Fatalf("expected a new session, got %v, %+v", journal, session)
+	}
+
+	recorded := &history.Session{
+		Model:   "model",
+		History: []*genai.Content{genai.NewContentFromText("q", genai.RoleUser)},
+	}
+	if err := journal.Record(recorded); err != nil {
+		t.Fatal(err)
+	}
+
+	_, session, err = openJournal(store, true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session == nil || session.Model != "model" || len(session.History) != 1 {
+		t.Errorf("expected the recorded session, got %+v", session)
+	}
+}
+
+func TestOpenJournalResume(t *testing.T) {
+	store := history.NewStore(t.TempDir())
+	if _, _, err := openJournal(store, false, "missing"); err == nil {
+		t.Error("expected error resuming a missing record")
+	}
+
+	conversation := history.NewConversation("notes", &history.Session{
+		History: []*genai.Content{genai.NewContentFromText("q", genai.RoleUser)},
+	})
+	if err := store.Save(conversation); err != nil {
+		t.Fatal(err)
+	}
+	for _, label := range []string{"notes", conversation.ID} {
+		_, session, err := openJournal(store, false, label)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if session == nil || len(session.History) != 1 {
+			t.Errorf("expected the stored session resuming %q, got %+v", label, session)
+		}
+	}
+}
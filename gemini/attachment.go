@@ -0,0 +1,169 @@
+package gemini
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/genai"
+)
+
+// MaxAttachmentSize is the maximum total size of the files attached to a chat
+// message. Inline data is limited by the total request size of 20MB.
+const MaxAttachmentSize = 20 << 20
+
+// textMIMEType is the MIME type used to send text-based files.
+const textMIMEType = "text/plain"
+
+// supportedMIMETypes maps the detected MIME types of the files to the MIME
+// types supported by the models for inline data.
+var supportedMIMETypes = map[string]string{
+	"image/png":        "image/png",
+	"image/jpeg":       "image/jpeg",
+	"image/webp":       "image/webp",
+	"image/heic":       "image/heic",
+	"image/heif":       "image/heif",
+	"application/pdf":  "application/pdf",
+	"audio/wav":        "audio/wav",
+	"audio/x-wav":      "audio/wav",
+	"audio/wave":       "audio/wav",
+	"audio/mp3":        "audio/mp3",
+	"audio/mpeg":       "audio/mp3",
+	"audio/aiff":       "audio/aiff",
+	"audio/x-aiff":     "audio/aiff",
+	"audio/aac":        "audio/aac",
+	"audio/ogg":        "audio/ogg",
+	"audio/flac":       "audio/flac",
+	"audio/x-flac":     "audio/flac",
+	"video/mp4":        "video/mp4",
+	"video/mpeg":       "video/mpeg",
+	"video/quicktime":  "video/mov",
+	"video/x-msvideo":  "video/avi",
+	"video/x-flv":      "video/x-flv",
+	"video/webm":       "video/webm",
+	"video/x-ms-wmv":   "video/wmv",
+	"video/3gpp":       "video/3gpp",
+	"application/json": textMIMEType,
+	"application/xml":  textMIMEType,
+	"application/yaml": textMIMEType,
+}
+
+// Attachment represents a local file attached to a chat message.
+type Attachment struct {
+	// Path is the file path.
+	Path string
+	// MIMEType is the MIME type of the file data sent to the model.
+	MIMEType string
+	// Data is the file content.
+	Data []byte
+}
+
+// NewAttachment reads the file at the given path and returns a new Attachment.
+// It returns an error if the file exceeds the MaxAttachmentSize limit or its
+// type is not supported.
+func NewAttachment(path string) (*Attachment, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error stating file: %w", err)
+	}
+
+	switch {
+	case info.IsDir():
+		return nil, fmt.Errorf("%s is a directory", path)
+	case info.Size() == 0:
+		return nil, fmt.Errorf("%s is empty", path)
+	case info.Size() > MaxAttachmentSize:
+		return nil, fmt.Errorf("%s exceeds the attachment size limit of %dMB",
+			path, MaxAttachmentSize>>20)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	mimeType, err := detectMIMEType(path, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &Attachment{
+		Path:     path,
+		MIMEType: mimeType,
+		Data:     data,
+	}, nil
+}
+
+// CheckAttachmentSize returns an error if the total size of the attachments
+// exceeds the MaxAttachmentSize limit. The error lists the files, starting from
+// the one pushing the total size over the limit.
+func CheckAttachmentSize(attachments []*Attachment) error {
+	var size int
+	for i, attachment := range attachments {
+		size += len(attachment.Data)
+		if size <= MaxAttachmentSize {
+			continue
+		}
+
+		paths := make([]string, 0, len(attachments)-i)
+		for _, exceeding := range attachments[i:] {
+			paths = append(paths, exceeding.Path)
+		}
+		return fmt.Errorf("the attached files exceed the total size limit of %dMB: %s",
+			MaxAttachmentSize>>20, strings.Join(paths, ", "))
+	}
+	return nil
+}
+
+// String returns a short description of the attachment.
+func (a *Attachment) String() string {
+	return fmt.Sprintf("%s (%s, %d bytes)", a.Path, a.MIMEType, len(a.Data))
+}
+
+// parts returns the message parts of the attachment. The file name is sent
+// in a text part preceding the data, so that it is visible in the chat history.
+func (a *Attachment) parts() []*genai.Part {
+	return []*genai.Part{
+		genai.NewPartFromText("Attached file: " + filepath.Base(a.Path)),
+		genai.NewPartFromBytes(a.Data, a.MIMEType),
+	}
+}
+
+// detectMIMEType returns the supported MIME type of the file, detected using
+// the file extension and falling back to the content sniffing.
+func detectMIMEType(path string, data []byte) (string, error) {
+	byExtension := mime.TypeByExtension(filepath.Ext(path))
+	for _, detected := range []string{byExtension, http.DetectContentType(data)} {
+		mediaType, _, err := mime.ParseMediaType(detected)
+		if err != nil {
+			continue
+		}
+
+		if mimeType, ok := supportedMIMETypes[mediaType]; ok {
+			return mimeType, nil
+		}
+		if strings.HasPrefix(mediaType, "text/") && utf8.Valid(data) {
+			return textMIMEType, nil
+		}
+	}
+
+	if byExtension == "" {
+		return "", errors.New("unsupported file type")
+	}
+	return "", fmt.Errorf("unsupported file type %q", byExtension)
+}
+
+// messageParts returns the parts of a chat message, composed of the attachments
+// followed by the text input.
+func messageParts(input string, attachments []*Attachment) []*genai.Part {
+	parts := make([]*genai.Part, 0, 2*len(attachments)+1)
+	for _, attachment := range attachments {
+		parts = append(parts, attachment.parts()...)
+	}
+	return append(parts, genai.NewPartFromText(input))
+}
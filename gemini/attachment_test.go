@@ -0,0 +1,50 @@
+package gemini
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckAttachmentSize(t *testing.T) {
+	newAttachment := func(path string, size int) *Attachment {
+		return &Attachment{Path: path, Data: make([]byte, size)}
+	}
+
+	tests := []struct {
+		name        string
+		attachments []*Attachment
+		// exceeding lists the files reported in the error, or nil if the
+		// attachments are within the limit
+		exceeding []string
+	}{
+		{"none", nil, nil},
+		{"single at limit", []*Attachment{newAttachment("a", MaxAttachmentSize)}, nil},
+		{"total at limit", []*Attachment{
+			newAttachment("a", MaxAttachmentSize/2),
+			newAttachment("b", MaxAttachmentSize/2),
+		}, nil},
+		{"total over limit", []*Attachment{
+			newAttachment("a", MaxAttachmentSize/2),
+			newAttachment("b", MaxAttachmentSize/4),
+			newAttachment("c", MaxAttachmentSize/2),
+			newAttachment("d", 1),
+		}, []string{"c", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckAttachmentSize(tt.attachments)
+			if tt.exceeding == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if _, files, _ := strings.Cut(err.Error(), ": "); files != strings.Join(tt.exceeding, ", ") {
+				t.Errorf("reported files = %q, want %q", files, tt.exceeding)
+			}
+		})
+	}
+}
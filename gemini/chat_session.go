@@ -3,8 +3,12 @@ package gemini
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"iter"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
 
 	"google.golang.org/genai"
@@ -12,54 +16,243 @@ import (
 
 const DefaultModel = "gemini-2.5-flash"
 
-// ChatSession represents a gemini powered chat session.
+// ChatSession represents a generative model powered chat session.
+// The requests are sent to the model using the session provider.
 type ChatSession struct {
 	ctx context.Context
 
-	client *genai.Client
-	chat   *genai.Chat
-	config *genai.GenerateContentConfig
-	model  string
+	provider Provider
+	history  []*genai.Content
+	config   *genai.GenerateContentConfig
+	model    string
 
 	loadModels sync.Once
 	models     []string
+
+	functions           map[string]Function
+	confirmFunctionCall FunctionCallConfirmation
+	maxFunctionCalls    int
+
+	retryPolicy RetryPolicy
+	notifyRetry RetryNotification
+
+	usage            map[string]*Usage
+	turnUsage        *Usage
+	turnGrounding    []*genai.GroundingMetadata
+	inputTokenLimits map[string]int32
+
+	// contextTokens is the size of the chat context reported by the last
+	// request, or zero if it is unknown.
+	contextTokens int32
 }
 
-// NewChatSession returns a new [ChatSession].
-func NewChatSession(ctx context.Context, model string,
-	contentConfig *genai.GenerateContentConfig) (*ChatSession, error) {
-	client, err := genai.NewClient(ctx, nil)
+// NewChatSession returns a new [ChatSession] using the given provider.
+func NewChatSession(ctx context.Context, provider Provider, model string,
+	contentConfig *genai.GenerateContentConfig) *ChatSession {
+	return &ChatSession{
+		ctx:              ctx,
+		provider:         provider,
+		config:           contentConfig,
+		model:            model,
+		functions:        make(map[string]Function),
+		maxFunctionCalls: DefaultMaxFunctionCalls,
+		retryPolicy:      DefaultRetryPolicy,
+		usage:            make(map[string]*Usage),
+		inputTokenLimits: make(map[string]int32),
+	}
+}
+
+// SendMessage sends a request to the model as part of a chat session.
+// The attached files are sent as inline data parts preceding the input text.
+// If the model requests function calls, the registered functions are executed
+// and their results are sent back until the model responds without function
+// calls. The request is aborted when the given context is canceled, in which
+// case the turn is not recorded in the chat history.
+func (c *ChatSession) SendMessage(ctx context.Context, input string,
+	attachments ...*Attachment) (*genai.GenerateContentResponse, error) {
+	c.turnUsage, c.turnGrounding = nil, nil
+	history := c.GetHistory()
+
+	response, err := c.send(ctx, messageParts(input, attachments))
+	for round := 0; err == nil && len(response.FunctionCalls()) > 0; round++ {
+		if round >= c.maxFunctionCalls {
+			err = fmt.Errorf("%w: %d rounds", ErrTooManyFunctionCalls, round)
+			break
+		}
+		response, err = c.send(ctx, c.callFunctions(ctx, response.FunctionCalls()))
+	}
+
 	if err != nil {
-		return nil, fmt.Errorf("failed to create client: %w", err)
+		return nil, errors.Join(err, c.restoreHistory(history))
 	}
+	return response, nil
+}
+
+// SendMessageStream is like SendMessage, but with streaming requests.
+// The turn is recorded in the chat history only if the stream is fully consumed.
+func (c *ChatSession) SendMessageStream(ctx context.Context, input string,
+	attachments ...*Attachment) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		c.turnUsage, c.turnGrounding = nil, nil
+		history := c.GetHistory()
+
+		parts := messageParts(input, attachments)
+		for round := 0; ; round++ {
+			var calls []*genai.FunctionCall
+			for response, err := range c.sendStream(ctx, parts) {
+				if err != nil {
+					yield(nil, errors.Join(err, c.restoreHistory(history)))
+					return
+				}
+				calls = append(calls, response.FunctionCalls()...)
+				if !yield(response, nil) {
+					_ = c.restoreHistory(history)
+					return
+				}
+			}
 
-	chat, err := client.Chats.Create(ctx, model, contentConfig, nil)
+			if len(calls) == 0 {
+				return
+			}
+			if round >= c.maxFunctionCalls {
+				err := fmt.Errorf("%w: %d rounds", ErrTooManyFunctionCalls, round)
+				yield(nil, errors.Join(err, c.restoreHistory(history)))
+				return
+			}
+			parts = c.callFunctions(ctx, calls)
+		}
+	}
+}
+
+// send sends the message parts to the model, recording the usage. The turn
+// is recorded in the chat history if the response content is valid, unless
+// the response was stopped early (e.g., blocked for safety reasons), so that
+// the message can be retried. The request is retried according to the retry
+// policy.
+func (c *ChatSession) send(ctx context.Context,
+	parts []*genai.Part) (*genai.GenerateContentResponse, error) {
+	message := genai.NewContentFromParts(parts, genai.RoleUser)
+	contents := append(slices.Clip(c.history), message)
+
+	var response *genai.GenerateContentResponse
+	retries, err := c.withRetry(ctx, func() (err error) {
+		response, err = c.provider.GenerateContent(ctx, c.model, contents, c.config)
+		return err
+	})
+	if err == nil {
+		err = promptBlockedError(response)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to create chat: %w", err)
+		return nil, c.requestError(err, retries)
 	}
 
-	return &ChatSession{
-		ctx:    ctx,
-		client: client,
-		chat:   chat,
-		config: contentConfig,
-		model:  model,
-	}, nil
+	c.recordUsage(response.UsageMetadata)
+	c.contextTokens = contextTokens(response.UsageMetadata)
+	if len(response.Candidates) > 0 {
+		candidate := response.Candidates[0]
+		c.recordGrounding(candidate.GroundingMetadata)
+		if validContent(candidate.Content) && (!finished(candidate.FinishReason) ||
+			completed(candidate.FinishReason)) {
+			c.history = append(c.history, message, candidate.Content)
+		}
+	}
+	return response, nil
 }
 
-// SendMessage sends a request to the model as part of a chat session.
-func (c *ChatSession) SendMessage(input string) (*genai.GenerateContentResponse, error) {
-	return c.chat.SendMessage(c.ctx, genai.Part{Text: input})
+// sendStream sends the message parts to the model using a streaming request,
+// recording the usage. The turn is recorded in the chat history if the stream
+// is fully consumed and completed, and all of the response chunks are valid.
+// The request is retried according to the retry policy, unless it fails
+// mid-stream.
+func (c *ChatSession) sendStream(ctx context.Context,
+	parts []*genai.Part) iter.Seq2[*genai.GenerateContentResponse, error] {
+	message := genai.NewContentFromParts(parts, genai.RoleUser)
+	contents := append(slices.Clip(c.history), message)
+	stream := c.streamWithRetry(ctx, func() iter.Seq2[*genai.GenerateContentResponse, error] {
+		return c.provider.GenerateContentStream(ctx, c.model, contents, c.config)
+	})
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		// the last chunk contains the usage metadata of the whole response
+		var usageMetadata *genai.GenerateContentResponseUsageMetadata
+		defer func() {
+			c.recordUsage(usageMetadata)
+			c.contextTokens = contextTokens(usageMetadata)
+		}()
+
+		var (
+			contents     []*genai.Content
+			valid        = true
+			finishReason genai.FinishReason
+		)
+		for response, err := range stream {
+			if err == nil {
+				err = c.requestError(promptBlockedError(response), 0)
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if response.UsageMetadata != nil {
+				usageMetadata = response.UsageMetadata
+			}
+			if len(response.Candidates) > 0 {
+				candidate := response.Candidates[0]
+				c.recordGrounding(candidate.GroundingMetadata)
+				if candidate.Content != nil {
+					valid = valid && validContent(candidate.Content)
+					contents = append(contents, candidate.Content)
+				}
+				if finished(candidate.FinishReason) {
+					finishReason = candidate.FinishReason
+				}
+			}
+			if !yield(response, nil) {
+				return
+			}
+		}
+
+		if valid && completed(finishReason) && len(contents) > 0 {
+			c.history = append(c.history, message)
+			c.history = append(c.history, contents...)
+		}
+	}
+}
+
+// callFunctions executes the function calls requested by the model and
+// returns the function response parts. The declined and failed calls are
+// reported to the model as errors.
+func (c *ChatSession) callFunctions(ctx context.Context, calls []*genai.FunctionCall) []*genai.Part {
+	parts := make([]*genai.Part, len(calls))
+	for i, call := range calls {
+		function, ok := c.functions[call.Name]
+		switch {
+		case !ok:
+			parts[i] = functionResponse(call, nil, fmt.Errorf("unknown function: %s", call.Name))
+		case c.confirmFunctionCall != nil && !c.confirmFunctionCall(call):
+			parts[i] = functionResponse(call, nil, errors.New("the user declined the function call"))
+		default:
+			result, err := function.Call(ctx, call.Args)
+			parts[i] = functionResponse(call, result, err)
+		}
+	}
+	return parts
+}
+
+// restoreHistory restores the chat history to the state preceding a failed
+// turn, which may have recorded intermediate function calls.
+func (c *ChatSession) restoreHistory(history []*genai.Content) error {
+	return c.SetHistory(history)
 }
 
-// SendMessageStream is like SendMessage, but with a streaming request.
-func (c *ChatSession) SendMessageStream(input string) iter.Seq2[*genai.GenerateContentResponse, error] {
-	return c.chat.SendMessageStream(c.ctx, genai.Part{Text: input})
+// Model returns the chat generative model name.
+func (c *ChatSession) Model() string {
+	return c.model
 }
 
 // ModelInfo returns information about the chat generative model in JSON format.
 func (c *ChatSession) ModelInfo() (string, error) {
-	modelInfo, err := c.client.Models.Get(c.ctx, c.model, nil)
+	modelInfo, err := c.provider.ModelInfo(c.ctx, c.model)
 	if err != nil {
 		return "", err
 	}
@@ -73,14 +266,12 @@ func (c *ChatSession) ModelInfo() (string, error) {
 }
 
 // ListModels returns a list of the supported generative model names.
+// If the provider fails to list the models, the current model is returned.
 func (c *ChatSession) ListModels() []string {
 	c.loadModels.Do(func() {
-		c.models = []string{DefaultModel}
-		for model, err := range c.client.Models.All(c.ctx) {
-			if err != nil {
-				continue
-			}
-			c.models = append(c.models, model.Name)
+		c.models, _ = c.provider.ListModels(c.ctx)
+		if !slices.Contains(c.models, c.model) {
+			c.models = append([]string{c.model}, c.models...)
 		}
 	})
 	return c.models
@@ -88,29 +279,26 @@ func (c *ChatSession) ListModels() []string {
 
 // SetModel sets the chat generative model.
 func (c *ChatSession) SetModel(model string) error {
-	chat, err := c.client.Chats.Create(c.ctx, model, c.config, c.GetHistory())
-	if err != nil {
-		return fmt.Errorf("failed to set model: %w", err)
-	}
-
 	c.model = model
-	c.chat = chat
+	c.contextTokens = 0
 	return nil
 }
 
 // GetHistory returns the chat session history.
 func (c *ChatSession) GetHistory() []*genai.Content {
-	return c.chat.History(true)
+	return slices.Clone(c.history)
 }
 
 // SetHistory sets the chat session history.
 func (c *ChatSession) SetHistory(history []*genai.Content) error {
-	chat, err := c.client.Chats.Create(c.ctx, c.model, c.config, history)
-	if err != nil {
-		return fmt.Errorf("failed to set history: %w", err)
+	for _, content := range history {
+		if content.Role != genai.RoleUser && content.Role != genai.RoleModel {
+			return fmt.Errorf("failed to set history: invalid content role %q", content.Role)
+		}
 	}
 
-	c.chat = chat
+	c.history = slices.Clone(history)
+	c.contextTokens = 0
 	return nil
 }
 
@@ -119,14 +307,203 @@ func (c *ChatSession) ClearHistory() error {
 	return c.SetHistory(nil)
 }
 
+// SystemInstruction returns the chat session system instruction.
+func (c *ChatSession) SystemInstruction() *genai.Content {
+	return c.config.SystemInstruction
+}
+
 // SetSystemInstruction sets the chat session system instruction.
 func (c *ChatSession) SetSystemInstruction(systemInstruction *genai.Content) error {
 	c.config.SystemInstruction = systemInstruction
-	chat, err := c.client.Chats.Create(c.ctx, c.model, c.config, c.GetHistory())
-	if err != nil {
-		return fmt.Errorf("failed to set system instruction: %w", err)
+	c.contextTokens = 0
+	return nil
+}
+
+// GenerationConfig returns the chat session generation parameters.
+func (c *ChatSession) GenerationConfig() *GenerationConfig {
+	return newGenerationConfig(c.config)
+}
+
+// SetGenerationConfig sets the chat session generation parameters.
+func (c *ChatSession) SetGenerationConfig(generationConfig *GenerationConfig) error {
+	generationConfig.Apply(c.config)
+	return nil
+}
+
+// SafetySettings returns the chat session safety settings.
+func (c *ChatSession) SafetySettings() []*genai.SafetySetting {
+	return slices.Clone(c.config.SafetySettings)
+}
+
+// SetSafetySettings sets the chat session safety settings.
+func (c *ChatSession) SetSafetySettings(safetySettings []*genai.SafetySetting) error {
+	c.config.SafetySettings = safetySettings
+	return nil
+}
+
+// RegisterFunctions registers the functions the model can call, replacing
+// the previously registered ones with the same names.
+func (c *ChatSession) RegisterFunctions(functions ...Function) error {
+	for _, function := range functions {
+		c.functions[function.Declaration().Name] = function
+	}
+	c.setTools(c.config.Tools)
+
+	return nil
+}
+
+// UnregisterFunctions removes the registered functions with the given names.
+func (c *ChatSession) UnregisterFunctions(names ...string) error {
+	for _, name := range names {
+		delete(c.functions, name)
+	}
+	c.setTools(c.config.Tools)
+
+	return nil
+}
+
+// SetTools sets the built-in tools of the chat session, such as Google Search.
+// The declarations of the registered functions are preserved.
+func (c *ChatSession) SetTools(tools []*genai.Tool) error {
+	c.setTools(tools)
+	return nil
+}
+
+// setTools sets the tools, replacing the function declarations tool with
+// the declarations of the registered functions.
+func (c *ChatSession) setTools(tools []*genai.Tool) {
+	tools = slices.DeleteFunc(slices.Clone(tools), func(tool *genai.Tool) bool {
+		return tool.FunctionDeclarations != nil
+	})
+	if len(c.functions) > 0 {
+		declarations := make([]*genai.FunctionDeclaration, 0, len(c.functions))
+		for _, function := range c.Functions() {
+			declarations = append(declarations, function.Declaration())
+		}
+		tools = append(tools, &genai.Tool{FunctionDeclarations: declarations})
 	}
+	c.config.Tools = tools
+}
+
+// Functions returns the registered functions sorted by name.
+func (c *ChatSession) Functions() []Function {
+	return slices.SortedFunc(maps.Values(c.functions), func(a, b Function) int {
+		return strings.Compare(a.Declaration().Name, b.Declaration().Name)
+	})
+}
+
+// SetFunctionCallConfirmation sets the confirmation called before executing
+// each function call. If not set, all function calls are executed.
+func (c *ChatSession) SetFunctionCallConfirmation(confirm FunctionCallConfirmation) {
+	c.confirmFunctionCall = confirm
+}
 
-	c.chat = chat
+// SetMaxFunctionCalls sets the maximum number of consecutive function call
+// rounds in a single chat turn, which must be positive.
+func (c *ChatSession) SetMaxFunctionCalls(maxFunctionCalls int) error {
+	if maxFunctionCalls < 1 {
+		return fmt.Errorf("invalid max function calls %d: must be at least 1", maxFunctionCalls)
+	}
+	c.maxFunctionCalls = maxFunctionCalls
 	return nil
 }
+
+// SetRetryPolicy sets the policy of retrying the failed requests.
+func (c *ChatSession) SetRetryPolicy(retryPolicy RetryPolicy) {
+	c.retryPolicy = retryPolicy
+}
+
+// RetryPolicy returns the policy of retrying the failed requests.
+func (c *ChatSession) RetryPolicy() RetryPolicy {
+	return c.retryPolicy
+}
+
+// SetRetryNotification sets the notification called before waiting to retry
+// a failed request.
+func (c *ChatSession) SetRetryNotification(notify RetryNotification) {
+	c.notifyRetry = notify
+}
+
+// Usage returns the cumulative token usage of the chat session requests,
+// keyed by the generative model name.
+func (c *ChatSession) Usage() map[string]Usage {
+	usage := make(map[string]Usage, len(c.usage))
+	for model, modelUsage := range c.usage {
+		usage[model] = *modelUsage
+	}
+	return usage
+}
+
+// TurnUsage returns the token usage of the last chat turn, including the
+// function call rounds. It returns nil if the usage is not available.
+func (c *ChatSession) TurnUsage() *Usage {
+	if c.turnUsage == nil {
+		return nil
+	}
+	usage := *c.turnUsage
+	return &usage
+}
+
+// TurnGroundingMetadata returns the grounding metadata of the responses of the
+// last chat turn, including the function call rounds, in the order received.
+func (c *ChatSession) TurnGroundingMetadata() []*genai.GroundingMetadata {
+	return slices.Clone(c.turnGrounding)
+}
+
+// recordGrounding adds the response grounding metadata to the chat turn.
+func (c *ChatSession) recordGrounding(metadata *genai.GroundingMetadata) {
+	if metadata != nil {
+		c.turnGrounding = append(c.turnGrounding, metadata)
+	}
+}
+
+// recordUsage adds the request usage to the chat session usage.
+func (c *ChatSession) recordUsage(metadata *genai.GenerateContentResponseUsageMetadata) {
+	usage := NewUsage(metadata)
+	if usage == nil {
+		return
+	}
+
+	if c.turnUsage == nil {
+		c.turnUsage = &Usage{}
+	}
+	c.turnUsage.Add(usage)
+
+	if modelUsage, ok := c.usage[c.model]; ok {
+		modelUsage.Add(usage)
+	} else {
+		c.usage[c.model] = usage
+	}
+}
+
+// finished reports whether the finish reason is set.
+func finished(reason genai.FinishReason) bool {
+	return reason != "" && reason != genai.FinishReasonUnspecified
+}
+
+// completed reports whether the response finished with the reason is complete,
+// or truncated at the output token limit, and can be continued. The responses
+// stopped for other reasons, such as safety, are not recorded in the history.
+func completed(reason genai.FinishReason) bool {
+	return reason == genai.FinishReasonStop || reason == genai.FinishReasonMaxTokens
+}
+
+// validContent returns true if the model response content can be recorded
+// in the chat history, i.e., it has parts, and none of them is empty.
+func validContent(content *genai.Content) bool {
+	if content == nil || len(content.Parts) == 0 {
+		return false
+	}
+
+	for _, part := range content.Parts {
+		if part == nil {
+			return false
+		}
+		if part.Text == "" && part.InlineData == nil && part.FileData == nil &&
+			part.FunctionCall == nil && part.FunctionResponse == nil &&
+			part.ExecutableCode == nil && part.CodeExecutionResult == nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+package gemini
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestSendMessageStreamRecording(t *testing.T) {
+	tests := []struct {
+		name         string
+		finishReason genai.FinishReason
+		recorded     bool
+	}{
+		{"stop", genai.FinishReasonStop, true},
+		{"max tokens", genai.FinishReasonMaxTokens, true},
+		{"safety", genai.FinishReasonSafety, false},
+		{"recitation", genai.FinishReasonRecitation, false},
+		{"prohibited content", genai.FinishReasonProhibitedContent, false},
+		{"unfinished", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeProvider{
+				streams: [][]*genai.GenerateContentResponse{{
+					textResponse("partial", ""),
+					textResponse(" answer", tt.finishReason),
+				}},
+			}
+			session := newTestSession(provider)
+			for _, err := range session.SendMessageStream(context.Background(), "question") {
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if tt.recorded {
+				assertHistoryText(t, session.GetHistory(), "question", "partial", " answer")
+			} else {
+				assertHistoryText(t, session.GetHistory())
+			}
+		})
+	}
+}
+
+func TestSendMessageRecording(t *testing.T) {
+	tests := []struct {
+		name         string
+		finishReason genai.FinishReason
+		recorded     bool
+	}{
+		{"stop", genai.FinishReasonStop, true},
+		{"max tokens", genai.FinishReasonMaxTokens, true},
+		{"unspecified", genai.FinishReasonUnspecified, true},
+		{"safety", genai.FinishReasonSafety, false},
+		{"recitation", genai.FinishReasonRecitation, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeProvider{
+				responses: []*genai.GenerateContentResponse{textResponse("answer", tt.finishReason)},
+			}
+			session := newTestSession(provider)
+			if _, err := session.SendMessage(context.Background(), "question"); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.recorded {
+				assertHistoryText(t, session.GetHistory(), "question", "answer")
+			} else {
+				assertHistoryText(t, session.GetHistory())
+			}
+		})
+	}
+}
+
+func TestSendMessageStreamRetryAfterBlock(t *testing.T) {
+	provider := &fakeProvider{
+		streams: [][]*genai.GenerateContentResponse{
+			{textResponse("partial", ""), {
+				Candidates: []*genai.Candidate{{FinishReason: genai.FinishReasonSafety}},
+			}},
+			{textResponse("full answer", genai.FinishReasonStop)},
+		},
+	}
+	session := newTestSession(provider)
+	for range 2 {
+		for _, err := range session.SendMessageStream(context.Background(), "question") {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	assertHistoryText(t, session.GetHistory(), "question", "full answer")
+	if n := len(provider.requests[1]); n != 1 {
+		t.Errorf("retried request contents = %d, want 1", n)
+	}
+}
@@ -0,0 +1,272 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// The type URLs of the API error details.
+const (
+	errorInfoType    = "type.googleapis.com/google.rpc.ErrorInfo"
+	retryInfoType    = "type.googleapis.com/google.rpc.RetryInfo"
+	quotaFailureType = "type.googleapis.com/google.rpc.QuotaFailure"
+)
+
+// ErrorKind represents the class of a failed request error.
+type ErrorKind int
+
+const (
+	// ErrorKindUnknown is the kind of the unclassified errors.
+	ErrorKindUnknown ErrorKind = iota
+	// ErrorKindInvalidRequest is the kind of the malformed request errors.
+	ErrorKindInvalidRequest
+	// ErrorKindAuthentication is the kind of the invalid or missing API key errors.
+	ErrorKindAuthentication
+	// ErrorKindPermission is the kind of the insufficient permission errors.
+	ErrorKindPermission
+	// ErrorKindNotFound is the kind of the unknown model errors.
+	ErrorKindNotFound
+	// ErrorKindRateLimit is the kind of the short-term rate limit errors.
+	ErrorKindRateLimit
+	// ErrorKindQuota is the kind of the exhausted quota errors.
+	ErrorKindQuota
+	// ErrorKindUnavailable is the kind of the server-side errors.
+	ErrorKindUnavailable
+	// ErrorKindNetwork is the kind of the connection errors.
+	ErrorKindNetwork
+	// ErrorKindBlocked is the kind of the prompts blocked by the safety filters.
+	ErrorKindBlocked
+)
+
+// retryable returns true if the errors of the kind are transient.
+func (k ErrorKind) retryable() bool {
+	return k == ErrorKindRateLimit || k == ErrorKindUnavailable || k == ErrorKindNetwork
+}
+
+// PromptBlockedError is returned when the prompt is blocked, and the model
+// does not generate a response.
+type PromptBlockedError struct {
+	// Feedback contains the block reason and the safety ratings of the prompt.
+	Feedback *genai.GenerateContentResponsePromptFeedback
+}
+
+func (e *PromptBlockedError) Error() string {
+	if e.Feedback.BlockReasonMessage != "" {
+		return e.Feedback.BlockReasonMessage
+	}
+	return fmt.Sprintf("block reason %s", e.Feedback.BlockReason)
+}
+
+// promptBlockedError returns the error if the response prompt feedback
+// indicates that the prompt is blocked; otherwise, it returns nil.
+func promptBlockedError(response *genai.GenerateContentResponse) error {
+	if response.PromptFeedback == nil || response.PromptFeedback.BlockReason == "" ||
+		response.PromptFeedback.BlockReason == genai.BlockedReasonUnspecified ||
+		len(response.Candidates) > 0 {
+		return nil
+	}
+	return &PromptBlockedError{Feedback: response.PromptFeedback}
+}
+
+// RequestError represents a classified model request error, described with
+// a hint on how to resolve it.
+type RequestError struct {
+	// Kind is the class of the error.
+	Kind ErrorKind
+	// Model is the name of the requested generative model.
+	Model string
+	// Retries is the number of the failed retries of the request.
+	Retries int
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *RequestError) Error() string {
+	description, hint := e.describe()
+	switch {
+	case e.Retries == 1:
+		description += " after 1 retry"
+	case e.Retries > 1:
+		description = fmt.Sprintf("%s after %d retries", description, e.Retries)
+	}
+
+	message := fmt.Sprintf("%s: %s", description, errorMessage(e.Err))
+	if hint != "" {
+		message += "\n" + hint
+	}
+	return message
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// describe returns the description and the resolution hint of the error.
+func (e *RequestError) describe() (string, string) {
+	switch e.Kind {
+	case ErrorKindInvalidRequest:
+		return "invalid request", ""
+	case ErrorKindAuthentication:
+		return "authentication failed",
+			"Check the API key (e.g., the GEMINI_API_KEY environment variable) or the provider settings."
+	case ErrorKindPermission:
+		return "permission denied",
+			"Check that the API key or credentials have access to the model, and the API is enabled."
+	case ErrorKindNotFound:
+		return fmt.Sprintf("model %q not found", e.Model),
+			"Select one of the available models."
+	case ErrorKindRateLimit:
+		return "rate limit exceeded",
+			"Wait a moment and try again, or increase the retry settings in the configuration file."
+	case ErrorKindQuota:
+		return "quota exhausted",
+			"Wait for the quota to reset, or check the plan and billing details of the account."
+	case ErrorKindUnavailable:
+		return "service unavailable",
+			"The model may be overloaded; try again later, or select another model."
+	case ErrorKindNetwork:
+		return "network error",
+			"Check the network connection and the provider base URL."
+	case ErrorKindBlocked:
+		return "the prompt was blocked",
+			"Rephrase the message, or adjust the safety_settings thresholds in the configuration file."
+	default:
+		return "request failed", ""
+	}
+}
+
+// requestError returns the classified request error. The unclassified
+// errors, including the canceled requests, are returned as is.
+func (c *ChatSession) requestError(err error, retries int) error {
+	var requestErr *RequestError
+	if errors.As(err, &requestErr) {
+		return err
+	}
+
+	kind := classifyError(err)
+	if kind == ErrorKindUnknown {
+		return err
+	}
+	return &RequestError{Kind: kind, Model: c.model, Retries: retries, Err: err}
+}
+
+// classifyError returns the kind of the request error.
+//
+//nolint:gocyclo
+func classifyError(err error) ErrorKind {
+	var blockedErr *PromptBlockedError
+	if errors.As(err, &blockedErr) {
+		return ErrorKindBlocked
+	}
+
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) {
+		if isNetworkError(err) {
+			return ErrorKindNetwork
+		}
+		return ErrorKindUnknown
+	}
+
+	reason := errorReason(apiErr)
+	switch {
+	case apiErr.Code == http.StatusUnauthorized || reason == "API_KEY_INVALID" ||
+		reason == "invalid_api_key":
+		return ErrorKindAuthentication
+	case apiErr.Code == http.StatusForbidden:
+		return ErrorKindPermission
+	case apiErr.Code == http.StatusNotFound:
+		return ErrorKindNotFound
+	case apiErr.Code == http.StatusTooManyRequests:
+		if reason == "insufficient_quota" || dailyQuotaExceeded(apiErr) {
+			return ErrorKindQuota
+		}
+		return ErrorKindRateLimit
+	case apiErr.Code >= http.StatusInternalServerError:
+		return ErrorKindUnavailable
+	case apiErr.Code >= http.StatusBadRequest:
+		return ErrorKindInvalidRequest
+	default:
+		return ErrorKindUnknown
+	}
+}
+
+// isNetworkError returns true if the error is a transient connection error.
+func isNetworkError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// errorReason returns the reason of the API error details, if any.
+func errorReason(apiErr genai.APIError) string {
+	for _, detail := range apiErr.Details {
+		if detail["@type"] == errorInfoType {
+			if reason, ok := detail["reason"].(string); ok {
+				return reason
+			}
+		}
+	}
+	return ""
+}
+
+// dailyQuotaExceeded returns true if the API error details indicate that
+// a daily quota is exceeded, which is not worth retrying.
+func dailyQuotaExceeded(apiErr genai.APIError) bool {
+	for _, detail := range apiErr.Details {
+		if detail["@type"] != quotaFailureType {
+			continue
+		}
+		violations, _ := detail["violations"].([]any)
+		for _, violation := range violations {
+			violation, _ := violation.(map[string]any)
+			if quotaID, _ := violation["quotaId"].(string); strings.Contains(quotaID, "PerDay") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// retryDelay returns the delay requested by the server in the API error
+// details, if any.
+func retryDelay(err error) (time.Duration, bool) {
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) {
+		return 0, false
+	}
+
+	for _, detail := range apiErr.Details {
+		if detail["@type"] != retryInfoType {
+			continue
+		}
+		if value, ok := detail["retryDelay"].(string); ok {
+			if delay, err := time.ParseDuration(value); err == nil {
+				return delay, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// errorMessage returns the message of the error, without the details of
+// the API errors.
+func errorMessage(err error) string {
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) && apiErr.Message != "" {
+		return apiErr.Message
+	}
+	return err.Error()
+}
@@ -0,0 +1,109 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// DefaultMaxFunctionCalls is the default maximum number of consecutive
+// function call rounds in a single chat turn.
+const DefaultMaxFunctionCalls = 10
+
+// ErrTooManyFunctionCalls is returned when the model keeps requesting function
+// calls beyond the maximum number of rounds in a single chat turn.
+var ErrTooManyFunctionCalls = errors.New("too many consecutive function calls")
+
+// Function represents a function the model can call.
+type Function interface {
+	// Declaration returns the function declaration provided to the model.
+	Declaration() *genai.FunctionDeclaration
+	// Call executes the function with the arguments provided by the model,
+	// and returns the result.
+	Call(ctx context.Context, args map[string]any) (map[string]any, error)
+}
+
+// FunctionCallConfirmation is called before executing a function call
+// requested by the model. The call is declined if it returns false.
+type FunctionCallConfirmation func(call *genai.FunctionCall) bool
+
+// CommandFunction is a Function executed as a local command. The call arguments
+// are written to the standard input of the command as a JSON object. The standard
+// output is returned as the function result, either as is if it contains a JSON
+// object, or as the "output" field otherwise.
+type CommandFunction struct {
+	declaration *genai.FunctionDeclaration
+	command     []string
+}
+
+var _ Function = (*CommandFunction)(nil)
+
+// NewCommandFunction returns a new CommandFunction.
+func NewCommandFunction(declaration *genai.FunctionDeclaration,
+	command []string) (*CommandFunction, error) {
+	if declaration.Name == "" {
+		return nil, errors.New("function name is empty")
+	}
+	if len(command) == 0 {
+		return nil, fmt.Errorf("function %q command is empty", declaration.Name)
+	}
+
+	return &CommandFunction{
+		declaration: declaration,
+		command:     command,
+	}, nil
+}
+
+// Declaration returns the function declaration provided to the model.
+func (f *CommandFunction) Declaration() *genai.FunctionDeclaration {
+	return f.declaration
+}
+
+// Call executes the command, passing the arguments to its standard input.
+func (f *CommandFunction) Call(ctx context.Context, args map[string]any) (map[string]any, error) {
+	if args == nil {
+		args = make(map[string]any)
+	}
+	input, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding arguments: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, f.command[0], f.command[1:]...) //nolint:gosec // user-configured
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%w: %s", err, message)
+		}
+		return nil, err
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(stdout.Bytes(), &result); err == nil && result != nil {
+		return result, nil
+	}
+
+	return map[string]any{"output": stdout.String()}, nil
+}
+
+// functionResponse returns the function response part for the call result.
+// Following the API convention, the "error" field is used for failed calls.
+func functionResponse(call *genai.FunctionCall, result map[string]any, err error) *genai.Part {
+	if err != nil {
+		result = map[string]any{"error": err.Error()}
+	}
+
+	part := genai.NewPartFromFunctionResponse(call.Name, result)
+	part.FunctionResponse.ID = call.ID
+	return part
+}
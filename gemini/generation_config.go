@@ -0,0 +1,247 @@
+package gemini
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// Generation parameter names.
+const (
+	ParamTemperature     = "temperature"
+	ParamTopP            = "top_p"
+	ParamTopK            = "top_k"
+	ParamMaxOutputTokens = "max_output_tokens"
+	ParamCandidateCount  = "candidate_count"
+	ParamStopSequences   = "stop_sequences"
+	ParamSeed            = "seed"
+	ParamThinkingBudget  = "thinking_budget"
+	ParamIncludeThoughts = "include_thoughts"
+)
+
+// GenerationParameters lists the names of the supported generation parameters.
+var GenerationParameters = []string{
+	ParamTemperature,
+	ParamTopP,
+	ParamTopK,
+	ParamMaxOutputTokens,
+	ParamCandidateCount,
+	ParamStopSequences,
+	ParamSeed,
+	ParamThinkingBudget,
+	ParamIncludeThoughts,
+}
+
+// GenerationConfig represents serializable model generation parameters.
+// Unset parameters fall back to the model defaults.
+type GenerationConfig struct {
+	// Controls the degree of randomness in token selection.
+	Temperature *float32 `json:"temperature,omitempty"`
+	// Tokens are selected until the sum of their probabilities equals this value.
+	TopP *float32 `json:"top_p,omitempty"`
+	// The number of the most probable tokens considered for each selection step.
+	TopK *float32 `json:"top_k,omitempty"`
+	// The maximum number of tokens that can be generated in the response.
+	MaxOutputTokens *int32 `json:"max_output_tokens,omitempty"`
+	// The number of response variations to return.
+	CandidateCount *int32 `json:"candidate_count,omitempty"`
+	// Character sequences that stop the output generation.
+	StopSequences []string `json:"stop_sequences,omitempty"`
+	// The seed used in decoding for reproducible results.
+	Seed *int32 `json:"seed,omitempty"`
+	// The number of thinking tokens the model can use. Zero disables thinking,
+	// and -1 enables dynamic thinking.
+	ThinkingBudget *int32 `json:"thinking_budget,omitempty"`
+	// Whether to include the thought summaries in the response.
+	IncludeThoughts *bool `json:"include_thoughts,omitempty"`
+}
+
+// newGenerationConfig returns a new GenerationConfig from the generation
+// parameters of the given [genai.GenerateContentConfig].
+func newGenerationConfig(config *genai.GenerateContentConfig) *GenerationConfig {
+	generationConfig := &GenerationConfig{
+		Temperature:     config.Temperature,
+		TopP:            config.TopP,
+		TopK:            config.TopK,
+		MaxOutputTokens: nonZeroOrNil(config.MaxOutputTokens),
+		CandidateCount:  nonZeroOrNil(config.CandidateCount),
+		StopSequences:   config.StopSequences,
+		Seed:            config.Seed,
+	}
+	if config.ThinkingConfig != nil {
+		generationConfig.ThinkingBudget = config.ThinkingConfig.ThinkingBudget
+		includeThoughts := config.ThinkingConfig.IncludeThoughts
+		generationConfig.IncludeThoughts = &includeThoughts
+	}
+
+	return generationConfig
+}
+
+// Merge returns a new GenerationConfig, where the parameters set in the other
+// config take precedence over the parameters of this config.
+func (g *GenerationConfig) Merge(other *GenerationConfig) *GenerationConfig {
+	merged := *g
+	if other == nil {
+		return &merged
+	}
+
+	if other.Temperature != nil {
+		merged.Temperature = other.Temperature
+	}
+	if other.TopP != nil {
+		merged.TopP = other.TopP
+	}
+	if other.TopK != nil {
+		merged.TopK = other.TopK
+	}
+	if other.MaxOutputTokens != nil {
+		merged.MaxOutputTokens = other.MaxOutputTokens
+	}
+	if other.CandidateCount != nil {
+		merged.CandidateCount = other.CandidateCount
+	}
+	if other.StopSequences != nil {
+		merged.StopSequences = other.StopSequences
+	}
+	if other.Seed != nil {
+		merged.Seed = other.Seed
+	}
+	if other.ThinkingBudget != nil {
+		merged.ThinkingBudget = other.ThinkingBudget
+	}
+	if other.IncludeThoughts != nil {
+		merged.IncludeThoughts = other.IncludeThoughts
+	}
+
+	return &merged
+}
+
+// Apply sets the generation parameters of the [genai.GenerateContentConfig].
+// Unset parameters are reset to the model defaults.
+func (g *GenerationConfig) Apply(config *genai.GenerateContentConfig) {
+	config.Temperature = g.Temperature
+	config.TopP = g.TopP
+	config.TopK = g.TopK
+	config.MaxOutputTokens = valueOrZero(g.MaxOutputTokens)
+	config.CandidateCount = valueOrZero(g.CandidateCount)
+	config.StopSequences = g.StopSequences
+	config.Seed = g.Seed
+
+	config.ThinkingConfig = nil
+	if g.ThinkingBudget != nil || g.IncludeThoughts != nil {
+		config.ThinkingConfig = &genai.ThinkingConfig{
+			IncludeThoughts: valueOrZero(g.IncludeThoughts),
+			ThinkingBudget:  g.ThinkingBudget,
+		}
+	}
+}
+
+// Get returns the string representation of the named parameter value.
+// An empty string is returned if the parameter is not set.
+func (g *GenerationConfig) Get(name string) string {
+	switch name {
+	case ParamTemperature:
+		return formatOptional(g.Temperature)
+	case ParamTopP:
+		return formatOptional(g.TopP)
+	case ParamTopK:
+		return formatOptional(g.TopK)
+	case ParamMaxOutputTokens:
+		return formatOptional(g.MaxOutputTokens)
+	case ParamCandidateCount:
+		return formatOptional(g.CandidateCount)
+	case ParamStopSequences:
+		return strings.Join(g.StopSequences, ",")
+	case ParamSeed:
+		return formatOptional(g.Seed)
+	case ParamThinkingBudget:
+		return formatOptional(g.ThinkingBudget)
+	case ParamIncludeThoughts:
+		return formatOptional(g.IncludeThoughts)
+	default:
+		return ""
+	}
+}
+
+// Set parses the value and sets the named parameter. Stop sequences are
+// specified as a comma-separated list. An empty value unsets the parameter.
+func (g *GenerationConfig) Set(name, value string) error {
+	var err error
+	switch name {
+	case ParamTemperature:
+		g.Temperature, err = parseOptional(value, parseFloat32)
+	case ParamTopP:
+		g.TopP, err = parseOptional(value, parseFloat32)
+	case ParamTopK:
+		g.TopK, err = parseOptional(value, parseFloat32)
+	case ParamMaxOutputTokens:
+		g.MaxOutputTokens, err = parseOptional(value, parseInt32)
+	case ParamCandidateCount:
+		g.CandidateCount, err = parseOptional(value, parseInt32)
+	case ParamStopSequences:
+		g.StopSequences = nil
+		if value != "" {
+			g.StopSequences = strings.Split(value, ",")
+		}
+	case ParamSeed:
+		g.Seed, err = parseOptional(value, parseInt32)
+	case ParamThinkingBudget:
+		g.ThinkingBudget, err = parseOptional(value, parseInt32)
+	case ParamIncludeThoughts:
+		g.IncludeThoughts, err = parseOptional(value, strconv.ParseBool)
+	default:
+		return fmt.Errorf("unknown generation parameter: %s", name)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid %s value: %w", name, err)
+	}
+	return nil
+}
+
+func parseOptional[T any](value string, parse func(string) (T, error)) (*T, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parsed, nil
+}
+
+func parseFloat32(value string) (float32, error) {
+	parsed, err := strconv.ParseFloat(value, 32)
+	return float32(parsed), err
+}
+
+func parseInt32(value string) (int32, error) {
+	parsed, err := strconv.ParseInt(value, 10, 32)
+	return int32(parsed), err
+}
+
+func formatOptional[T float32 | int32 | bool](value *T) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(*value)
+}
+
+func valueOrZero[T any](value *T) T {
+	var zero T
+	if value == nil {
+		return zero
+	}
+	return *value
+}
+
+func nonZeroOrNil(value int32) *int32 {
+	if value == 0 {
+		return nil
+	}
+	return &value
+}
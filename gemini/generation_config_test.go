@@ -0,0 +1,42 @@
+package gemini
+
+import (
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestNewGenerationConfigThinking(t *testing.T) {
+	budget := int32(0)
+	tests := []struct {
+		name            string
+		thinkingConfig  *genai.ThinkingConfig
+		includeThoughts string
+		thinkingBudget  string
+	}{
+		{"unset", nil, "", ""},
+		{"budget only", &genai.ThinkingConfig{ThinkingBudget: &budget}, "false", "0"},
+		{"include thoughts", &genai.ThinkingConfig{IncludeThoughts: true}, "true", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newGenerationConfig(&genai.GenerateContentConfig{ThinkingConfig: tt.thinkingConfig})
+			if value := config.Get(ParamIncludeThoughts); value != tt.includeThoughts {
+				t.Errorf("include thoughts = %q, want %q", value, tt.includeThoughts)
+			}
+			if value := config.Get(ParamThinkingBudget); value != tt.thinkingBudget {
+				t.Errorf("thinking budget = %q, want %q", value, tt.thinkingBudget)
+			}
+
+			// the parameters survive the round trip
+			applied := &genai.GenerateContentConfig{}
+			config.Apply(applied)
+			roundTrip := newGenerationConfig(applied)
+			for _, name := range GenerationParameters {
+				if value, want := roundTrip.Get(name), config.Get(name); value != want {
+					t.Errorf("round trip %s = %q, want %q", name, value, want)
+				}
+			}
+		})
+	}
+}
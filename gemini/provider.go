@@ -0,0 +1,88 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"iter"
+
+	"google.golang.org/genai"
+)
+
+// Provider represents a generative model backend the chat session sends its
+// requests to. The requests and responses are represented using the genai
+// types regardless of the backend, and the chat state (history, system
+// instruction and generation parameters) is kept by the session.
+type Provider interface {
+	// GenerateContent generates the model response to the contents.
+	GenerateContent(ctx context.Context, model string, contents []*genai.Content,
+		config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error)
+	// GenerateContentStream is like GenerateContent, but the response is
+	// streamed in chunks.
+	GenerateContentStream(ctx context.Context, model string, contents []*genai.Content,
+		config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error]
+	// CountTokens returns the number of tokens in the contents.
+	CountTokens(ctx context.Context, model string, contents []*genai.Content) (int32, error)
+	// ListModels returns the names of the available generative models.
+	ListModels(ctx context.Context) ([]string, error)
+	// ModelInfo returns information about the generative model. The input
+	// token limit is zero if unknown.
+	ModelInfo(ctx context.Context, model string) (*genai.Model, error)
+}
+
+// GeminiProvider is the default Provider, backed by the Gemini API.
+type GeminiProvider struct {
+	client *genai.Client
+}
+
+var _ Provider = (*GeminiProvider)(nil)
+
+// NewGeminiProvider returns a new [GeminiProvider]. If the client config is
+// nil, the client is configured using the environment variables.
+func NewGeminiProvider(ctx context.Context, clientConfig *genai.ClientConfig) (*GeminiProvider, error) {
+	client, err := genai.NewClient(ctx, clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	return &GeminiProvider{client: client}, nil
+}
+
+// GenerateContent generates the model response to the contents.
+func (p *GeminiProvider) GenerateContent(ctx context.Context, model string, contents []*genai.Content,
+	config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	return p.client.Models.GenerateContent(ctx, model, contents, config)
+}
+
+// GenerateContentStream is like GenerateContent, but the response is streamed in chunks.
+func (p *GeminiProvider) GenerateContentStream(ctx context.Context, model string, contents []*genai.Content,
+	config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return p.client.Models.GenerateContentStream(ctx, model, contents, config)
+}
+
+// CountTokens returns the number of tokens in the contents.
+func (p *GeminiProvider) CountTokens(ctx context.Context, model string,
+	contents []*genai.Content) (int32, error) {
+	response, err := p.client.Models.CountTokens(ctx, model, contents, nil)
+	if err != nil {
+		return 0, err
+	}
+	return response.TotalTokens, nil
+}
+
+// ListModels returns the names of the available generative models,
+// starting with the default model.
+func (p *GeminiProvider) ListModels(ctx context.Context) ([]string, error) {
+	models := []string{DefaultModel}
+	for model, err := range p.client.Models.All(ctx) {
+		if err != nil {
+			return models, err
+		}
+		models = append(models, model.Name)
+	}
+	return models, nil
+}
+
+// ModelInfo returns information about the generative model.
+func (p *GeminiProvider) ModelInfo(ctx context.Context, model string) (*genai.Model, error) {
+	return p.client.Models.Get(ctx, model, nil)
+}
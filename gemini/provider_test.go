@@ -0,0 +1,102 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"iter"
+
+	"google.golang.org/genai"
+)
+
+// fakeProvider is a Provider returning the queued results of the requests.
+type fakeProvider struct {
+	// errs are returned by the requests in order, preceding the responses.
+	errs []error
+	// responses are returned by GenerateContent in order.
+	responses []*genai.GenerateContentResponse
+	// streams are returned by GenerateContentStream in order.
+	streams [][]*genai.GenerateContentResponse
+	// requests contains the contents of the generation requests.
+	requests [][]*genai.Content
+	// counted contains the contents of the token counting requests.
+	counted [][]*genai.Content
+}
+
+var _ Provider = (*fakeProvider)(nil)
+
+func (p *fakeProvider) GenerateContent(_ context.Context, _ string, contents []*genai.Content,
+	_ *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	p.requests = append(p.requests, contents)
+	if err := p.nextError(); err != nil {
+		return nil, err
+	}
+	if len(p.responses) == 0 {
+		return nil, errors.New("no response queued")
+	}
+	response := p.responses[0]
+	p.responses = p.responses[1:]
+	return response, nil
+}
+
+func (p *fakeProvider) GenerateContentStream(_ context.Context, _ string, contents []*genai.Content,
+	_ *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	p.requests = append(p.requests, contents)
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		if err := p.nextError(); err != nil {
+			yield(nil, err)
+			return
+		}
+		if len(p.streams) == 0 {
+			yield(nil, errors.New("no stream queued"))
+			return
+		}
+		stream := p.streams[0]
+		p.streams = p.streams[1:]
+		for _, response := range stream {
+			if !yield(response, nil) {
+				return
+			}
+		}
+	}
+}
+
+// CountTokens counts each content as a single token.
+func (p *fakeProvider) CountTokens(_ context.Context, _ string, contents []*genai.Content) (int32, error) {
+	p.counted = append(p.counted, contents)
+	return int32(len(contents)), nil
+}
+
+func (p *fakeProvider) ListModels(context.Context) ([]string, error) {
+	return []string{DefaultModel}, nil
+}
+
+func (p *fakeProvider) ModelInfo(_ context.Context, model string) (*genai.Model, error) {
+	return &genai.Model{Name: model, InputTokenLimit: 1000}, nil
+}
+
+func (p *fakeProvider) nextError() error {
+	if len(p.errs) == 0 {
+		return nil
+	}
+	err := p.errs[0]
+	p.errs = p.errs[1:]
+	return err
+}
+
+// newTestSession returns a new chat session using the provider, which
+// does not wait before retrying the requests.
+func newTestSession(provider Provider) *ChatSession {
+	session := NewChatSession(context.Background(), provider, DefaultModel, &genai.GenerateContentConfig{})
+	session.SetRetryPolicy(RetryPolicy{MaxRetries: 3})
+	return session
+}
+
+// textResponse returns a response with the model text, finished with the reason.
+func textResponse(text string, finishReason genai.FinishReason) *genai.GenerateContentResponse {
+	return &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{
+			Content:      genai.NewContentFromText(text, genai.RoleModel),
+			FinishReason: finishReason,
+		}},
+	}
+}
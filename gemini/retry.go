@@ -0,0 +1,137 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// DefaultRetryPolicy is the default policy of retrying the failed requests.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:   3,
+	InitialDelay: time.Second,
+	MaxDelay:     time.Minute,
+	Multiplier:   2,
+	Jitter:       0.2,
+}
+
+// RetryPolicy represents the policy of retrying the requests failed with
+// transient errors, such as rate limiting or service unavailability.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of retries of a request.
+	// Zero disables retrying.
+	MaxRetries int
+	// InitialDelay is the delay before the first retry.
+	InitialDelay time.Duration
+	// MaxDelay is the maximum delay between retries. The requests are not
+	// retried if the server asks to wait longer.
+	MaxDelay time.Duration
+	// Multiplier is the factor the delay is multiplied by after each retry.
+	Multiplier float64
+	// Jitter is the fraction of the delay, by which it is randomly adjusted
+	// in both directions.
+	Jitter float64
+}
+
+// RetryNotification is called before waiting to retry a failed request,
+// with the retry number starting at 1.
+type RetryNotification func(retry int, delay time.Duration, err error)
+
+// delay returns the delay before the given retry of the request failed with
+// the error. The delay requested by the server takes precedence over the
+// exponential backoff. It returns false if the request should not be retried.
+func (p *RetryPolicy) delay(retry int, err error) (time.Duration, bool) {
+	if retry > p.MaxRetries || !classifyError(err).retryable() {
+		return 0, false
+	}
+
+	backoff := float64(p.InitialDelay) * math.Pow(max(p.Multiplier, 1), float64(retry-1))
+	if p.Jitter > 0 {
+		backoff *= 1 + p.Jitter*(2*rand.Float64()-1) //nolint:gosec // not security-sensitive
+	}
+	delay := time.Duration(min(backoff, float64(p.MaxDelay)))
+
+	if retryAfter, ok := retryDelay(err); ok {
+		if retryAfter > p.MaxDelay {
+			return 0, false
+		}
+		delay = max(delay, retryAfter)
+	}
+
+	return delay, true
+}
+
+// withRetry calls the request, retrying it according to the retry policy.
+// It returns the number of retries along with the last error.
+func (c *ChatSession) withRetry(ctx context.Context, request func() error) (int, error) {
+	for retry := 1; ; retry++ {
+		err := request()
+		if err == nil {
+			return retry - 1, nil
+		}
+		if !c.waitRetry(ctx, retry, err) {
+			return retry - 1, err
+		}
+	}
+}
+
+// waitRetry waits before the given retry of the request failed with the
+// error. It returns false if the request should not be retried, or the
+// context is canceled while waiting.
+func (c *ChatSession) waitRetry(ctx context.Context, retry int, err error) bool {
+	delay, ok := c.retryPolicy.delay(retry, err)
+	if !ok || ctx.Err() != nil {
+		return false
+	}
+	if c.notifyRetry != nil {
+		c.notifyRetry(retry, delay, err)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// streamWithRetry returns the response stream, retrying the request if it
+// fails before yielding any response chunks.
+func (c *ChatSession) streamWithRetry(ctx context.Context,
+	stream func() iter.Seq2[*genai.GenerateContentResponse, error]) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		var started bool
+		for retry := 1; ; retry++ {
+			var retrying bool
+			for response, err := range stream() {
+				if errors.Is(err, io.EOF) {
+					return
+				}
+				if err != nil {
+					if !started && c.waitRetry(ctx, retry, err) {
+						retrying = true
+						break
+					}
+					yield(nil, c.requestError(err, retry-1))
+					return
+				}
+
+				started = true
+				if !yield(response, nil) {
+					return
+				}
+			}
+			if !retrying {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func TestClassifyError(t *testing.T) {
+	errorInfo := func(reason string) map[string]any {
+		return map[string]any{"@type": errorInfoType, "reason": reason}
+	}
+	dailyQuota := map[string]any{
+		"@type":      quotaFailureType,
+		"violations": []any{map[string]any{"quotaId": "GenerateRequestsPerDayPerProjectPerModel"}},
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		kind ErrorKind
+	}{
+		{"bad request", apiError(http.StatusBadRequest), ErrorKindInvalidRequest},
+		{"invalid api key", apiError(http.StatusBadRequest, errorInfo("API_KEY_INVALID")), ErrorKindAuthentication},
+		{"unauthorized", apiError(http.StatusUnauthorized), ErrorKindAuthentication},
+		{"forbidden", apiError(http.StatusForbidden), ErrorKindPermission},
+		{"not found", apiError(http.StatusNotFound), ErrorKindNotFound},
+		{"rate limit", apiError(http.StatusTooManyRequests), ErrorKindRateLimit},
+		{"insufficient quota", apiError(http.StatusTooManyRequests, errorInfo("insufficient_quota")), ErrorKindQuota},
+		{"daily quota", apiError(http.StatusTooManyRequests, dailyQuota), ErrorKindQuota},
+		{"internal", apiError(http.StatusInternalServerError), ErrorKindUnavailable},
+		{"unavailable", apiError(http.StatusServiceUnavailable), ErrorKindUnavailable},
+		{"wrapped", fmt.Errorf("wrapped: %w", apiError(http.StatusServiceUnavailable)), ErrorKindUnavailable},
+		{"connection", &net.OpError{Op: "dial", Err: errors.New("refused")}, ErrorKindNetwork},
+		{"unexpected eof", io.ErrUnexpectedEOF, ErrorKindNetwork},
+		{"canceled", context.Canceled, ErrorKindUnknown},
+		{"deadline", context.DeadlineExceeded, ErrorKindUnknown},
+		{"blocked", &PromptBlockedError{Feedback: &genai.GenerateContentResponsePromptFeedback{}}, ErrorKindBlocked},
+		{"unknown", errors.New("unknown"), ErrorKindUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if kind := classifyError(tt.err); kind != tt.kind {
+				t.Errorf("kind = %d, want %d", kind, tt.kind)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{
+		MaxRetries:   3,
+		InitialDelay: time.Second,
+		MaxDelay:     10 * time.Second,
+		Multiplier:   4,
+	}
+	retryInfo := func(delay string) map[string]any {
+		return map[string]any{"@type": retryInfoType, "retryDelay": delay}
+	}
+
+	tests := []struct {
+		name  string
+		retry int
+		err   error
+		// delay is the expected delay, or -1 if the request is not retried
+		delay time.Duration
+	}{
+		{"first retry", 1, apiError(http.StatusServiceUnavailable), time.Second},
+		{"backoff", 2, apiError(http.StatusTooManyRequests), 4 * time.Second},
+		{"max delay", 3, apiError(http.StatusServiceUnavailable), 10 * time.Second},
+		{"max retries", 4, apiError(http.StatusServiceUnavailable), -1},
+		{"not retryable", 1, apiError(http.StatusBadRequest), -1},
+		{"quota", 1, apiError(http.StatusTooManyRequests, map[string]any{
+			"@type": errorInfoType, "reason": "insufficient_quota",
+		}), -1},
+		{"retry delay", 1, apiError(http.StatusTooManyRequests, retryInfo("5s")), 5 * time.Second},
+		{"shorter retry delay", 2, apiError(http.StatusTooManyRequests, retryInfo("2s")), 4 * time.Second},
+		{"retry delay above max", 1, apiError(http.StatusTooManyRequests, retryInfo("30s")), -1},
+		{"network", 1, io.ErrUnexpectedEOF, time.Second},
+		{"canceled", 1, context.Canceled, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, ok := policy.delay(tt.retry, tt.err)
+			if tt.delay < 0 {
+				if ok {
+					t.Errorf("unexpected retry after %s", delay)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("expected retry")
+			}
+			if delay != tt.delay {
+				t.Errorf("delay = %s, want %s", delay, tt.delay)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyJitter(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 1, InitialDelay: time.Second, MaxDelay: time.Minute, Jitter: 0.2}
+	for range 100 {
+		delay, ok := policy.delay(1, apiError(http.StatusServiceUnavailable))
+		if !ok {
+			t.Fatal("expected retry")
+		}
+		if delay < 800*time.Millisecond || delay > 1200*time.Millisecond {
+			t.Fatalf("delay = %s, want within 20%% of 1s", delay)
+		}
+	}
+}
+
+func TestSendMessageRetries(t *testing.T) {
+	unavailable := apiError(http.StatusServiceUnavailable)
+	tests := []struct {
+		name     string
+		errs     []error
+		requests int
+		// retries is the number of retries reported by the error,
+		// or -1 if the request succeeds
+		retries int
+	}{
+		{"recovered", []error{unavailable, unavailable}, 3, -1},
+		{"max retries", []error{unavailable, unavailable, unavailable, unavailable}, 4, 3},
+		{"not retryable", []error{apiError(http.StatusBadRequest)}, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeProvider{
+				errs:      tt.errs,
+				responses: []*genai.GenerateContentResponse{textResponse("a", genai.FinishReasonStop)},
+			}
+			session := newTestSession(provider)
+			var notified int
+			session.SetRetryNotification(func(int, time.Duration, error) { notified++ })
+
+			_, err := session.SendMessage(context.Background(), "q")
+			if len(provider.requests) != tt.requests {
+				t.Errorf("requests = %d, want %d", len(provider.requests), tt.requests)
+			}
+			if tt.retries < 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if notified != len(tt.errs) {
+					t.Errorf("notified = %d, want %d", notified, len(tt.errs))
+				}
+				return
+			}
+
+			var requestErr *RequestError
+			if !errors.As(err, &requestErr) {
+				t.Fatalf("expected RequestError, got %v", err)
+			}
+			if requestErr.Retries != tt.retries {
+				t.Errorf("retries = %d, want %d", requestErr.Retries, tt.retries)
+			}
+			if notified != tt.retries {
+				t.Errorf("notified = %d, want %d", notified, tt.retries)
+			}
+		})
+	}
+}
+
+func TestSendMessageCanceledBackoff(t *testing.T) {
+	provider := &fakeProvider{errs: []error{apiError(http.StatusServiceUnavailable)}}
+	session := newTestSession(provider)
+	session.SetRetryPolicy(RetryPolicy{MaxRetries: 3, InitialDelay: time.Hour, MaxDelay: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// cancel the request while waiting for the first retry
+	session.SetRetryNotification(func(int, time.Duration, error) { cancel() })
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := session.SendMessage(ctx, "q")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if classifyError(err) != ErrorKindUnavailable {
+			t.Errorf("expected the request error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the backoff was not interrupted by the cancellation")
+	}
+	if len(provider.requests) != 1 {
+		t.Errorf("requests = %d, want 1", len(provider.requests))
+	}
+}
+
+// apiError returns a new API error with the HTTP status code and details.
+func apiError(code int, details ...map[string]any) error {
+	return genai.APIError{Code: code, Message: http.StatusText(code), Details: details}
+}
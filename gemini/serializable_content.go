@@ -1,43 +1,109 @@
 package gemini
 
 import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
 	"google.golang.org/genai"
 )
 
-// SerializableContent is the data type containing multipart text message content.
-// It is a serializable equivalent of [genai.Content], where message content parts
-// are represented as strings.
+// lostContentText is the text of the part replacing the legacy content parts,
+// if all of them contained non-text data lost in serialization, since the
+// model rejects messages without parts.
+const lostContentText = "[non-text content lost]"
+
+// SerializableContentVersion is the current version of the SerializableContent
+// serialization format.
+//   - Version 1 (no version field): message parts are represented as strings,
+//     containing only the text of the parts.
+//   - Version 2: message parts are represented as [genai.Part] objects,
+//     preserving all part types. Binary data is base64-encoded.
+const SerializableContentVersion = 2
+
+// SerializableContent is the data type containing multipart message content.
+// It is a serializable equivalent of [genai.Content].
 type SerializableContent struct {
+	// The serialization format version.
+	Version int
 	// Ordered parts that constitute a single message.
-	Parts []string
+	Parts []*genai.Part
 	// The producer of the content. Must be either 'user' or 'model'.
 	Role string
 }
 
 // NewSerializableContent instantiates and returns a new SerializableContent from
 // the given [genai.Content].
-// It will panic if the content type is not supported.
 func NewSerializableContent(c *genai.Content) *SerializableContent {
-	parts := make([]string, len(c.Parts))
-	for i, part := range c.Parts {
-		parts[i] = part.Text
-	}
-
 	return &SerializableContent{
-		Parts: parts,
-		Role:  c.Role,
+		Version: SerializableContentVersion,
+		Parts:   slices.Clone(c.Parts),
+		Role:    c.Role,
 	}
 }
 
 // ToContent converts the SerializableContent into a [genai.Content].
 func (c *SerializableContent) ToContent() *genai.Content {
-	parts := make([]*genai.Part, len(c.Parts))
-	for i, part := range c.Parts {
-		parts[i] = genai.NewPartFromText(part)
-	}
-
 	return &genai.Content{
-		Parts: parts,
+		Parts: slices.Clone(c.Parts),
 		Role:  c.Role,
 	}
 }
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface.
+// Content serialized in a previous format version is migrated to the current one.
+func (c *SerializableContent) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Version int
+		Parts   []json.RawMessage
+		Role    string
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	if raw.Version > SerializableContentVersion {
+		return fmt.Errorf("unsupported content format version: %d", raw.Version)
+	}
+
+	parts := make([]*genai.Part, 0, len(raw.Parts))
+	for _, rawPart := range raw.Parts {
+		part, err := unmarshalPart(raw.Version, rawPart)
+		if err != nil {
+			return err
+		}
+		if part != nil {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 && len(raw.Parts) > 0 {
+		parts = append(parts, genai.NewPartFromText(lostContentText))
+	}
+
+	c.Version = SerializableContentVersion
+	c.Parts = parts
+	c.Role = raw.Role
+
+	return nil
+}
+
+// unmarshalPart decodes a message part serialized using the given format version.
+// It returns nil for legacy empty text parts, which represent lost non-text data.
+func unmarshalPart(version int, data json.RawMessage) (*genai.Part, error) {
+	if version < 2 {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return nil, fmt.Errorf("error decoding legacy content part: %w", err)
+		}
+		if text == "" {
+			return nil, nil
+		}
+		return genai.NewPartFromText(text), nil
+	}
+
+	part := &genai.Part{}
+	if err := json.Unmarshal(data, part); err != nil {
+		return nil, fmt.Errorf("error decoding content part: %w", err)
+	}
+	return part, nil
+}
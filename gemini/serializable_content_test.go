@@ -0,0 +1,87 @@
+package gemini
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestSerializableContentLegacy(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		texts []string
+	}{
+		{"text parts", `{"Parts": ["a", "b"], "Role": "user"}`, []string{"a", "b"}},
+		{"lost data part", `{"Parts": ["", "a"], "Role": "user"}`, []string{"a"}},
+		{"lost content", `{"Parts": ["", ""], "Role": "model"}`, []string{lostContentText}},
+		{"no parts", `{"Parts": [], "Role": "model"}`, []string{}},
+		{"version 1", `{"Version": 1, "Parts": ["a"], "Role": "user"}`, []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := &SerializableContent{}
+			if err := json.Unmarshal([]byte(tt.data), content); err != nil {
+				t.Fatal(err)
+			}
+			if content.Version != SerializableContentVersion {
+				t.Errorf("version = %d, want %d", content.Version, SerializableContentVersion)
+			}
+			texts := make([]string, len(content.Parts))
+			for i, part := range content.Parts {
+				texts[i] = part.Text
+			}
+			if !reflect.DeepEqual(texts, tt.texts) {
+				t.Errorf("texts = %q, want %q", texts, tt.texts)
+			}
+		})
+	}
+}
+
+func TestSerializableContentInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"future version", `{"Version": 3, "Parts": [], "Role": "user"}`},
+		{"legacy non-string part", `{"Parts": [{"text": "a"}], "Role": "user"}`},
+		{"invalid part", `{"Version": 2, "Parts": ["a"], "Role": "user"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := json.Unmarshal([]byte(tt.data), &SerializableContent{}); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestSerializableContentRoundTrip(t *testing.T) {
+	parts := []*genai.Part{
+		genai.NewPartFromText("text"),
+		{Text: "thought", Thought: true, ThoughtSignature: []byte{1, 2, 3}},
+		genai.NewPartFromBytes([]byte{0, 0xff, 'a'}, "image/png"),
+		genai.NewPartFromURI("gs://bucket/file.pdf", "application/pdf"),
+		genai.NewPartFromFunctionCall("lookup", map[string]any{"query": "a"}),
+		genai.NewPartFromFunctionResponse("lookup", map[string]any{"output": "b"}),
+		genai.NewPartFromExecutableCode("print(1)", genai.LanguagePython),
+		genai.NewPartFromCodeExecutionResult(genai.OutcomeOK, "1\n"),
+	}
+	for _, part := range parts {
+		content := genai.NewContentFromParts([]*genai.Part{part}, genai.RoleModel)
+		data, err := json.Marshal(NewSerializableContent(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded := &SerializableContent{}
+		if err := json.Unmarshal(data, decoded); err != nil {
+			t.Fatal(err)
+		}
+		if restored := decoded.ToContent(); !reflect.DeepEqual(restored, content) {
+			t.Errorf("round trip of %s = %+v, want %+v", data, restored.Parts[0], part)
+		}
+	}
+}
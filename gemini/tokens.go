@@ -0,0 +1,255 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"google.golang.org/genai"
+)
+
+// charsPerToken is the approximate number of characters per token, used to
+// estimate the size of the pending message.
+const charsPerToken = 4
+
+const summaryPrompt = "Summarize our conversation so far. Preserve the facts, decisions, " +
+	"code snippets and open questions required to continue the conversation. " +
+	"Respond with the summary only."
+
+// ErrMessageTooLarge is returned when the pending message does not fit into
+// the token limit, even if the chat history is removed entirely.
+var ErrMessageTooLarge = errors.New("the message exceeds the token limit")
+
+// ContextSize represents the size of the chat context in tokens.
+type ContextSize struct {
+	// The number of tokens in the chat context.
+	Tokens int32
+	// The maximum number of input tokens allowed by the model.
+	// Zero if the limit is unknown.
+	InputTokenLimit int32
+}
+
+// Ratio returns the fraction of the model input token limit used by the
+// chat context. Zero is returned if the limit is unknown.
+func (s *ContextSize) Ratio() float64 {
+	if s.InputTokenLimit <= 0 {
+		return 0
+	}
+	return float64(s.Tokens) / float64(s.InputTokenLimit)
+}
+
+// InputTokenLimit returns the maximum number of input tokens allowed by the
+// chat generative model. The limit is retrieved once per model.
+func (c *ChatSession) InputTokenLimit(ctx context.Context) (int32, error) {
+	if limit, ok := c.inputTokenLimits[c.model]; ok {
+		return limit, nil
+	}
+
+	modelInfo, err := c.provider.ModelInfo(ctx, c.model)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get model info: %w", err)
+	}
+
+	c.inputTokenLimits[c.model] = modelInfo.InputTokenLimit
+	return modelInfo.InputTokenLimit, nil
+}
+
+// CountTokens returns the size of the chat context, consisting of the system
+// instruction, the chat history, and the pending message with its attachments.
+// An empty input with no attachments counts the current context only.
+func (c *ChatSession) CountTokens(ctx context.Context, input string,
+	attachments ...*Attachment) (*ContextSize, error) {
+	limit, err := c.InputTokenLimit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := c.countTokens(ctx, c.GetHistory(), pendingMessage(input, attachments))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ContextSize{
+		Tokens:          tokens,
+		InputTokenLimit: limit,
+	}, nil
+}
+
+// EstimateTokens returns the estimated size of the chat context in tokens,
+// including the pending message, based on the usage reported by the last
+// request. The attachments are estimated by their data size, which tends to
+// overestimate the binary files. It returns false if the estimate is not
+// available, e.g., before the first request or after the history is replaced.
+func (c *ChatSession) EstimateTokens(input string, attachments ...*Attachment) (int32, bool) {
+	if c.contextTokens <= 0 {
+		return 0, false
+	}
+
+	size := len(input)
+	for _, attachment := range attachments {
+		size += len(attachment.Data)
+	}
+	tokens := int64(c.contextTokens) + int64(size/charsPerToken)
+	return int32(min(tokens, math.MaxInt32)), true
+}
+
+// TrimHistory removes the minimum number of the oldest chat turns required
+// for the context, including the pending message, to fit into maxTokens.
+// It returns the number of removed turns.
+func (c *ChatSession) TrimHistory(ctx context.Context, maxTokens int32, input string,
+	attachments ...*Attachment) (int, error) {
+	turns := splitTurns(c.GetHistory())
+	message := pendingMessage(input, attachments)
+	fits := func(removed int) (bool, error) {
+		tokens, err := c.countTokens(ctx, slices.Concat(turns[removed:]...), message)
+		return tokens <= maxTokens, err
+	}
+
+	ok, err := fits(len(turns))
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, ErrMessageTooLarge
+	}
+
+	// binary search for the minimum number of turns to remove
+	low, high := 0, len(turns)
+	for low < high {
+		mid := (low + high) / 2
+		ok, err := fits(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+
+	if low == 0 {
+		return 0, nil
+	}
+	if err := c.SetHistory(slices.Concat(turns[low:]...)); err != nil {
+		return 0, err
+	}
+
+	return low, nil
+}
+
+// Turns returns the number of the chat turns in the history.
+func (c *ChatSession) Turns() int {
+	return len(splitTurns(c.history))
+}
+
+// SummarizeHistory replaces the chat turns preceding the last keepTurns turns
+// with a model-generated summary. It returns the number of summarized turns.
+func (c *ChatSession) SummarizeHistory(ctx context.Context, keepTurns int) (int, error) {
+	turns := splitTurns(c.GetHistory())
+	summarized := len(turns) - max(keepTurns, 0)
+	if summarized <= 0 {
+		return 0, nil
+	}
+
+	request := genai.NewContentFromText(summaryPrompt, genai.RoleUser)
+	contents := append(slices.Concat(turns[:summarized]...), request)
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: c.config.SystemInstruction,
+	}
+
+	var response *genai.GenerateContentResponse
+	retries, err := c.withRetry(ctx, func() (err error) {
+		response, err = c.provider.GenerateContent(ctx, c.model, contents, config)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to summarize history: %w", c.requestError(err, retries))
+	}
+	c.recordUsage(response.UsageMetadata)
+
+	summary := response.Text()
+	if summary == "" {
+		return 0, errors.New("failed to summarize history: empty response")
+	}
+
+	history := append([]*genai.Content{
+		request,
+		genai.NewContentFromText(summary, genai.RoleModel),
+	}, slices.Concat(turns[summarized:]...)...)
+	if err := c.SetHistory(history); err != nil {
+		return 0, err
+	}
+
+	return summarized, nil
+}
+
+// countTokens returns the number of tokens in the system instruction, the
+// history and the message.
+func (c *ChatSession) countTokens(ctx context.Context, history []*genai.Content,
+	message *genai.Content) (int32, error) {
+	contents := make([]*genai.Content, 0, len(history)+2)
+	if c.config.SystemInstruction != nil {
+		// token counting with a system instruction is not supported by the
+		// Gemini API, so it is counted as a part of the contents
+		contents = append(contents, genai.NewContentFromParts(
+			c.config.SystemInstruction.Parts, genai.RoleUser))
+	}
+	contents = append(contents, history...)
+	if message != nil {
+		contents = append(contents, message)
+	}
+	if len(contents) == 0 {
+		return 0, nil
+	}
+
+	tokens, err := c.provider.CountTokens(ctx, c.model, contents)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// contextTokens returns the size of the chat context following the response,
+// i.e. the prompt and the response tokens, or zero if the usage is unknown.
+func contextTokens(metadata *genai.GenerateContentResponseUsageMetadata) int32 {
+	if metadata == nil {
+		return 0
+	}
+	return metadata.PromptTokenCount + metadata.CandidatesTokenCount
+}
+
+// pendingMessage returns the content of the message to be sent,
+// or nil if there is none.
+func pendingMessage(input string, attachments []*Attachment) *genai.Content {
+	if input == "" && len(attachments) == 0 {
+		return nil
+	}
+	return genai.NewContentFromParts(messageParts(input, attachments), genai.RoleUser)
+}
+
+// splitTurns splits the chat history into turns, each starting with a user
+// message and containing the subsequent model responses. The function
+// responses continue the turn, so that the function calls are never separated
+// from their responses.
+func splitTurns(history []*genai.Content) [][]*genai.Content {
+	var turns [][]*genai.Content
+	for _, content := range history {
+		if startsTurn(content) || len(turns) == 0 {
+			turns = append(turns, nil)
+		}
+		turns[len(turns)-1] = append(turns[len(turns)-1], content)
+	}
+	return turns
+}
+
+// startsTurn reports whether the content is a user message, other than
+// a function response.
+func startsTurn(content *genai.Content) bool {
+	return content.Role == genai.RoleUser && slices.ContainsFunc(content.Parts, func(part *genai.Part) bool {
+		return part.FunctionResponse == nil
+	})
+}
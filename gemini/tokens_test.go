@@ -0,0 +1,143 @@
+package gemini
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+// functionCallHistory returns a history of three turns, where the second one
+// contains a function call round.
+func functionCallHistory() []*genai.Content {
+	return []*genai.Content{
+		genai.NewContentFromText("q1", genai.RoleUser),
+		genai.NewContentFromText("a1", genai.RoleModel),
+		genai.NewContentFromText("q2", genai.RoleUser),
+		genai.NewContentFromFunctionCall("lookup", map[string]any{"q": "q2"}, genai.RoleModel),
+		genai.NewContentFromFunctionResponse("lookup", map[string]any{"output": "x"}, genai.RoleUser),
+		genai.NewContentFromText("a2", genai.RoleModel),
+		genai.NewContentFromText("q3", genai.RoleUser),
+		genai.NewContentFromText("a3", genai.RoleModel),
+	}
+}
+
+func TestSplitTurns(t *testing.T) {
+	history := functionCallHistory()
+	turns := splitTurns(history)
+
+	lengths := make([]int, len(turns))
+	for i, turn := range turns {
+		lengths[i] = len(turn)
+	}
+	if want := []int{2, 4, 2}; !slices.Equal(lengths, want) {
+		t.Errorf("turn lengths = %v, want %v", lengths, want)
+	}
+	if !slices.Equal(slices.Concat(turns...), history) {
+		t.Error("turns do not preserve the history")
+	}
+}
+
+func TestTurns(t *testing.T) {
+	session := newTestSession(&fakeProvider{})
+	if turns := session.Turns(); turns != 0 {
+		t.Errorf("turns = %d, want 0", turns)
+	}
+	if err := session.SetHistory(functionCallHistory()); err != nil {
+		t.Fatal(err)
+	}
+	if turns := session.Turns(); turns != 3 {
+		t.Errorf("turns = %d, want 3", turns)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	response := textResponse("a1", genai.FinishReasonStop)
+	response.UsageMetadata = &genai.GenerateContentResponseUsageMetadata{
+		PromptTokenCount:     100,
+		CandidatesTokenCount: 20,
+		ThoughtsTokenCount:   50,
+	}
+	session := newTestSession(&fakeProvider{responses: []*genai.GenerateContentResponse{response}})
+	if _, ok := session.EstimateTokens("q2"); ok {
+		t.Error("expected no estimate before the first request")
+	}
+
+	if _, err := session.SendMessage(context.Background(), "q1"); err != nil {
+		t.Fatal(err)
+	}
+	attachment := &Attachment{Path: "data.bin", Data: make([]byte, 400)}
+	tokens, ok := session.EstimateTokens("12345678", attachment)
+	if !ok {
+		t.Fatal("expected an estimate after the request")
+	}
+	if tokens != 222 {
+		t.Errorf("tokens = %d, want 222", tokens)
+	}
+
+	if err := session.SetHistory(session.GetHistory()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := session.EstimateTokens("q2"); ok {
+		t.Error("expected no estimate after the history is replaced")
+	}
+}
+
+func TestTrimHistoryFunctionCallRound(t *testing.T) {
+	session := newTestSession(&fakeProvider{})
+	if err := session.SetHistory(functionCallHistory()); err != nil {
+		t.Fatal(err)
+	}
+
+	// removing the first turn and the question of the second one would fit
+	// five contents, but the function call round must be kept whole
+	removed, err := session.TrimHistory(context.Background(), 5, "q4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("removed turns = %d, want 2", removed)
+	}
+	assertHistoryText(t, session.GetHistory(), "q3", "a3")
+}
+
+func TestSummarizeHistoryFunctionCallRound(t *testing.T) {
+	provider := &fakeProvider{
+		responses: []*genai.GenerateContentResponse{textResponse("summary", genai.FinishReasonStop)},
+	}
+	session := newTestSession(provider)
+	if err := session.SetHistory(functionCallHistory()); err != nil {
+		t.Fatal(err)
+	}
+
+	summarized, err := session.SummarizeHistory(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summarized != 1 {
+		t.Errorf("summarized turns = %d, want 1", summarized)
+	}
+
+	history := session.GetHistory()
+	if len(history) != 8 {
+		t.Fatalf("history length = %d, want 8", len(history))
+	}
+	if history[3].Parts[0].FunctionCall == nil || history[4].Parts[0].FunctionResponse == nil {
+		t.Error("the function call round is not kept whole")
+	}
+}
+
+// assertHistoryText checks the text of the history messages.
+func assertHistoryText(t *testing.T, history []*genai.Content, texts ...string) {
+	t.Helper()
+	actual := make([]string, len(history))
+	for i, content := range history {
+		for _, part := range content.Parts {
+			actual[i] += part.Text
+		}
+	}
+	if !slices.Equal(actual, texts) {
+		t.Errorf("history = %q, want %q", actual, texts)
+	}
+}
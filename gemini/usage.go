@@ -0,0 +1,92 @@
+package gemini
+
+import (
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// Usage represents the token usage of model requests.
+type Usage struct {
+	// The number of requests.
+	Requests int64
+	// The number of tokens in the prompts, including the cached tokens.
+	PromptTokens int64
+	// The number of tokens in the prompts served from the cache.
+	CachedTokens int64
+	// The number of tokens in the results of tool executions.
+	ToolUsePromptTokens int64
+	// The number of tokens in the generated candidates.
+	CandidatesTokens int64
+	// The number of tokens in the generated thoughts.
+	ThoughtsTokens int64
+	// The total number of tokens.
+	TotalTokens int64
+}
+
+// NewUsage returns the Usage of a single request from the response usage metadata.
+// It returns nil if the metadata is nil.
+func NewUsage(metadata *genai.GenerateContentResponseUsageMetadata) *Usage {
+	if metadata == nil {
+		return nil
+	}
+
+	return &Usage{
+		Requests:            1,
+		PromptTokens:        int64(metadata.PromptTokenCount),
+		CachedTokens:        int64(metadata.CachedContentTokenCount),
+		ToolUsePromptTokens: int64(metadata.ToolUsePromptTokenCount),
+		CandidatesTokens:    int64(metadata.CandidatesTokenCount),
+		ThoughtsTokens:      int64(metadata.ThoughtsTokenCount),
+		TotalTokens:         int64(metadata.TotalTokenCount),
+	}
+}
+
+// Add adds the other usage to this one.
+func (u *Usage) Add(other *Usage) {
+	u.Requests += other.Requests
+	u.PromptTokens += other.PromptTokens
+	u.CachedTokens += other.CachedTokens
+	u.ToolUsePromptTokens += other.ToolUsePromptTokens
+	u.CandidatesTokens += other.CandidatesTokens
+	u.ThoughtsTokens += other.ThoughtsTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// Pricing represents serializable model pricing in USD per one million tokens.
+type Pricing struct {
+	// The price of the prompt tokens, including the tool use prompt tokens.
+	Input float64 `json:"input"`
+	// The price of the prompt tokens served from the cache.
+	// If not set, the input price is used.
+	CachedInput *float64 `json:"cached_input,omitempty"`
+	// The price of the generated tokens, including the thoughts.
+	Output float64 `json:"output"`
+}
+
+// Cost returns the estimated cost of the usage in USD.
+func (p *Pricing) Cost(usage *Usage) float64 {
+	cachedInput := p.Input
+	if p.CachedInput != nil {
+		cachedInput = *p.CachedInput
+	}
+
+	inputTokens := usage.PromptTokens - usage.CachedTokens + usage.ToolUsePromptTokens
+	outputTokens := usage.CandidatesTokens + usage.ThoughtsTokens
+
+	return (float64(inputTokens)*p.Input +
+		float64(usage.CachedTokens)*cachedInput +
+		float64(outputTokens)*p.Output) / 1e6
+}
+
+// ModelPricing returns the pricing of the model from the pricing table keyed
+// by model name. The "models/" name prefix is ignored.
+func ModelPricing(pricing map[string]Pricing, model string) (*Pricing, bool) {
+	trimmed := strings.TrimPrefix(model, "models/")
+	for name, modelPricing := range pricing {
+		if strings.TrimPrefix(name, "models/") == trimmed {
+			return &modelPricing, true
+		}
+	}
+	return nil, false
+}
@@ -1,16 +1,22 @@
 package chat
 
 import (
+	"fmt"
+
 	"github.com/reugn/gemini-cli/gemini"
 	"github.com/reugn/gemini-cli/internal/cli"
 	"github.com/reugn/gemini-cli/internal/config"
 	"github.com/reugn/gemini-cli/internal/handler"
+	"github.com/reugn/gemini-cli/internal/history"
 	"github.com/reugn/gemini-cli/internal/terminal"
 )
 
 // Chat handles the interactive exchange of messages between user and model.
 type Chat struct {
-	io *terminal.IO
+	io              *terminal.IO
+	session         *gemini.ChatSession
+	applicationData *config.ApplicationData
+	journal         *history.Journal
 
 	geminiHandler handler.MessageHandler
 	systemHandler handler.MessageHandler
@@ -32,23 +38,31 @@ func New(
 		return nil, err
 	}
 
+	attachments := &handler.Attachments{}
+	sources := &handler.Sources{}
+	queryOptions := opts.queryOptions(configuration.Data)
+
 	geminiIO := handler.NewIO(terminalIO, terminalIO.Prompt.Gemini)
-	geminiHandler, err := handler.NewGeminiQuery(geminiIO, session, opts.rendererOptions())
+	geminiHandler, err := handler.NewGeminiQuery(geminiIO, session, attachments, sources,
+		&queryOptions, opts.rendererOptions())
 	if err != nil {
 		return nil, err
 	}
 
 	systemIO := handler.NewIO(terminalIO, terminalIO.Prompt.Cli)
 	systemHandler, err := handler.NewSystemCommand(systemIO, session, configuration,
-		opts.GenerativeModel, opts.rendererOptions())
+		attachments, sources, opts.systemCommandOptions(&queryOptions), opts.rendererOptions())
 	if err != nil {
 		return nil, err
 	}
 
 	return &Chat{
-		io:            terminalIO,
-		geminiHandler: geminiHandler,
-		systemHandler: systemHandler,
+		io:              terminalIO,
+		session:         session,
+		applicationData: configuration.Data,
+		journal:         opts.Journal,
+		geminiHandler:   geminiHandler,
+		systemHandler:   systemHandler,
 	}, nil
 }
 
@@ -74,6 +88,9 @@ func (c *Chat) Start() {
 		// write the response
 		c.io.Write(response.String())
 
+		// record the turn in the autosave journal
+		c.autosave()
+
 		if quit {
 			break
 		}
@@ -85,6 +102,18 @@ func (c *Chat) Close() error {
 	return c.io.Close()
 }
 
+// autosave records the chat session state in the autosave journal.
+// The failure is reported, but does not interrupt the chat.
+func (c *Chat) autosave() {
+	if c.journal == nil {
+		return
+	}
+	systemPrompt := c.applicationData.SystemPromptLabel(c.session.SystemInstruction())
+	if err := c.journal.Record(history.NewSession(c.session, systemPrompt)); err != nil {
+		c.io.Write(fmt.Sprintf("%s\n", terminal.Error(err.Error())))
+	}
+}
+
 // getHandler returns the handler for the message.
 func (c *Chat) getHandler(prefix string) handler.MessageHandler {
 	if prefix == cli.SystemCmdPrefix {
@@ -1,14 +1,42 @@
 package chat
 
-import "github.com/reugn/gemini-cli/internal/handler"
+import (
+	"maps"
+
+	"github.com/reugn/gemini-cli/gemini"
+	"github.com/reugn/gemini-cli/internal/config"
+	"github.com/reugn/gemini-cli/internal/handler"
+	"github.com/reugn/gemini-cli/internal/history"
+	"github.com/reugn/gemini-cli/internal/mcp"
+)
 
 // Opts represents the Chat configuration options.
 type Opts struct {
 	GenerativeModel string
+	SystemPrompt    string
 	Multiline       bool
 	LineTerminator  string
 	StylePath       string
 	WordWrap        int
+	Stream          bool
+	Raw             bool
+	Thoughts        string
+	Citations       bool
+	Usage           bool
+	JSON            bool
+	// Schema is the name of the configured response schema or the path to
+	// a JSON schema file, which implies the JSON output mode.
+	Schema string
+	// ResponseSchema is the resolved response schema.
+	ResponseSchema map[string]any
+	// GenerationConfig contains the command line generation parameters.
+	GenerationConfig *gemini.GenerationConfig
+	// MCPServers contains the configured MCP servers.
+	MCPServers mcp.Servers
+	// HistoryStore is the store of the chat history records.
+	HistoryStore *history.Store
+	// Journal is the autosave journal recording the turns of the session.
+	Journal *history.Journal
 }
 
 func (o *Opts) rendererOptions() handler.RendererOptions {
@@ -17,3 +45,30 @@ func (o *Opts) rendererOptions() handler.RendererOptions {
 		WordWrap:  o.WordWrap,
 	}
 }
+
+func (o *Opts) queryOptions(data *config.ApplicationData) handler.QueryOptions {
+	approvedFunctions := data.ApprovedFunctions()
+	maps.Copy(approvedFunctions, o.MCPServers.ApprovedFunctions())
+
+	return handler.QueryOptions{
+		Stream:            o.Stream,
+		Raw:               o.Raw,
+		Thoughts:          handler.ThoughtsMode(o.Thoughts),
+		Citations:         o.Citations,
+		Usage:             o.Usage,
+		JSON:              o.JSON,
+		Schema:            o.ResponseSchema,
+		Pricing:           data.Pricing,
+		Compaction:        data.Compaction,
+		ApprovedFunctions: approvedFunctions,
+	}
+}
+
+func (o *Opts) systemCommandOptions(queryOptions *handler.QueryOptions) handler.SystemCommandOptions {
+	return handler.SystemCommandOptions{
+		GenerationConfig: o.GenerationConfig,
+		QueryOptions:     queryOptions,
+		MCPServers:       o.MCPServers,
+		HistoryStore:     o.HistoryStore,
+	}
+}
@@ -0,0 +1,32 @@
+package chat
+
+import (
+	"errors"
+	"os"
+
+	"github.com/reugn/gemini-cli/gemini"
+	"github.com/reugn/gemini-cli/internal/config"
+	"github.com/reugn/gemini-cli/internal/handler"
+	"github.com/reugn/gemini-cli/internal/history"
+)
+
+// Query sends a single message to the model and writes the response to
+// the standard output. It is used in the non-interactive mode. The turn is
+// recorded in the autosave journal, if there is one.
+func Query(session *gemini.ChatSession, configuration *config.Configuration,
+	message string, opts *Opts) (err error) {
+	query, err := handler.NewSingleQuery(os.Stdout, session, opts.queryOptions(configuration.Data),
+		opts.rendererOptions())
+	if err != nil {
+		return err
+	}
+
+	if opts.Journal != nil {
+		defer func() {
+			systemPrompt := configuration.Data.SystemPromptLabel(session.SystemInstruction())
+			err = errors.Join(err, opts.Journal.Record(history.NewSession(session, systemPrompt)))
+		}()
+	}
+
+	return query.Run(message)
+}
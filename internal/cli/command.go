@@ -7,5 +7,14 @@ const (
 	SystemCmdSelectPrompt    = "p"
 	SystemCmdSelectInputMode = "i"
 	SystemCmdModel           = "m"
+	SystemCmdGeneration      = "g"
+	SystemCmdThoughts        = "t"
 	SystemCmdHistory         = "h"
+	SystemCmdAttach          = "attach"
+	SystemCmdCompact         = "compact"
+	SystemCmdTokens          = "tokens"
+	SystemCmdSources         = "sources"
+	SystemCmdTools           = "tools"
+	SystemCmdUsage           = "usage"
+	SystemCmdSettings        = "settings"
 )
@@ -1,6 +1,14 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
 	"github.com/reugn/gemini-cli/gemini"
 	"google.golang.org/genai"
 )
@@ -13,15 +21,38 @@ const (
 )
 
 const (
-	toolGoogleSearch = "GOOGLE_SEARCH"
-	toolURLContext   = "URL_CONTEXT"
+	toolGoogleSearch  = "GOOGLE_SEARCH"
+	toolURLContext    = "URL_CONTEXT"
+	toolCodeExecution = "CODE_EXECUTION"
 )
 
 // Threshold is a custom type that wraps genai.HarmBlockThreshold
 // and uses the custom string for serialization.
 type Threshold string
 
-func (t Threshold) toGenai() genai.HarmBlockThreshold {
+// Thresholds contains the safety settings threshold values, from the one
+// blocking the most to the one blocking nothing.
+var Thresholds = []Threshold{thresholdLow, thresholdMedium, thresholdHigh, thresholdOff}
+
+// NewThreshold returns the Threshold of the genai threshold value.
+// An empty Threshold is returned for the unspecified value.
+func NewThreshold(threshold genai.HarmBlockThreshold) Threshold {
+	switch threshold {
+	case genai.HarmBlockThresholdBlockLowAndAbove:
+		return thresholdLow
+	case genai.HarmBlockThresholdBlockMediumAndAbove:
+		return thresholdMedium
+	case genai.HarmBlockThresholdBlockOnlyHigh:
+		return thresholdHigh
+	case genai.HarmBlockThresholdBlockNone, genai.HarmBlockThresholdOff:
+		return thresholdOff
+	default:
+		return ""
+	}
+}
+
+// Genai returns the genai threshold value.
+func (t Threshold) Genai() genai.HarmBlockThreshold {
 	switch t {
 	case thresholdLow:
 		return genai.HarmBlockThresholdBlockLowAndAbove
@@ -43,19 +74,111 @@ type SafetySetting struct {
 	Threshold Threshold          `json:"threshold"`
 }
 
-// Tool represents a model tool configuration.
+// Tool represents a model tool configuration. A tool with a command is
+// a function tool, declared to the model and executed locally when called.
 type Tool struct {
 	Name    string `json:"name"`
 	Enabled bool   `json:"enabled"`
+	// The function description for the model.
+	Description string `json:"description,omitempty"`
+	// The JSON schema of the function parameters.
+	Parameters map[string]any `json:"parameters,omitempty"`
+	// The command executed when the model calls the function.
+	Command []string `json:"command,omitempty"`
+	// Whether the function is called without confirmation.
+	AutoApprove bool `json:"auto_approve,omitempty"`
+}
+
+// isFunction returns true if the tool is a function tool.
+func (t *Tool) isFunction() bool {
+	return len(t.Command) > 0
+}
+
+// MCPServer represents a Model Context Protocol server configuration.
+// The server is launched as a subprocess using the stdio transport.
+type MCPServer struct {
+	Command string            `json:"command"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	Enabled bool              `json:"enabled"`
+	// Whether the server tools are called without confirmation.
+	AutoApprove bool `json:"auto_approve,omitempty"`
+}
+
+// Compaction represents the chat history compaction configuration.
+type Compaction struct {
+	// Enabled enables the automatic compaction of the chat history.
+	Enabled bool `json:"enabled"`
+	// The number of tokens in the chat context, above which the history
+	// is compacted automatically.
+	Threshold int32 `json:"threshold"`
+	// The number of the most recent chat turns preserved verbatim.
+	KeepTurns int `json:"keep_turns"`
+}
+
+// Retry represents the configuration of retrying the requests failed with
+// transient errors. The delays are in seconds.
+type Retry struct {
+	// The maximum number of retries of a request; zero disables retrying.
+	MaxRetries int `json:"max_retries"`
+	// The delay before the first retry.
+	InitialDelay float64 `json:"initial_delay"`
+	// The maximum delay between retries.
+	MaxDelay float64 `json:"max_delay"`
+	// The factor the delay is multiplied by after each retry.
+	Multiplier float64 `json:"multiplier"`
+	// The fraction of the delay, by which it is randomly adjusted.
+	Jitter float64 `json:"jitter"`
+}
+
+// RetryPolicy converts the retry configuration to a chat session retry policy.
+func (r *Retry) RetryPolicy() gemini.RetryPolicy {
+	return gemini.RetryPolicy{
+		MaxRetries:   r.MaxRetries,
+		InitialDelay: seconds(r.InitialDelay),
+		MaxDelay:     seconds(r.MaxDelay),
+		Multiplier:   r.Multiplier,
+		Jitter:       r.Jitter,
+	}
+}
+
+// seconds converts the number of seconds to a duration.
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
 }
 
 // ApplicationData encapsulates application state and configuration.
-// Note that the chat history is stored in plain text format.
 type ApplicationData struct {
-	SystemPrompts  map[string]gemini.SystemInstruction      `json:"system_prompts"`
-	SafetySettings []SafetySetting                          `json:"safety_settings"`
-	Tools          []Tool                                   `json:"tools"`
-	History        map[string][]*gemini.SerializableContent `json:"history"`
+	SystemPrompts  map[string]gemini.SystemInstruction `json:"system_prompts"`
+	SafetySettings []SafetySetting                     `json:"safety_settings"`
+	Tools          []Tool                              `json:"tools"`
+	// Provider contains the generative model provider settings.
+	Provider Provider `json:"provider"`
+	// MaxFunctionCalls is the maximum number of consecutive function call
+	// rounds in a single chat turn.
+	MaxFunctionCalls int `json:"max_function_calls"`
+	// MCPServers contains the Model Context Protocol server configurations,
+	// keyed by the server name.
+	MCPServers map[string]MCPServer `json:"mcp_servers"`
+	// GenerationConfig contains the default generation parameters.
+	GenerationConfig gemini.GenerationConfig `json:"generation_config"`
+	// GenerationConfigOverrides contains the generation parameters specific to
+	// system prompts, keyed by the system prompt label.
+	GenerationConfigOverrides map[string]gemini.GenerationConfig `json:"generation_config_overrides"`
+	// Pricing contains the optional model pricing used to estimate the usage cost,
+	// keyed by the generative model name.
+	Pricing map[string]gemini.Pricing `json:"pricing"`
+	// Compaction contains the chat history compaction settings.
+	Compaction Compaction `json:"compaction"`
+	// Retry contains the settings of retrying the failed requests.
+	Retry Retry `json:"retry"`
+	// Schemas contains the JSON schemas of the structured responses,
+	// keyed by the schema name.
+	Schemas map[string]map[string]any `json:"schemas"`
+	// History contains the chat history records stored by the earlier versions
+	// of the application, keyed by label. The records are migrated to the
+	// history store on startup.
+	History map[string][]*gemini.SerializableContent `json:"history,omitempty"`
 }
 
 // newDefaultApplicationData returns a new ApplicationData with default values.
@@ -70,24 +193,65 @@ func newDefaultApplicationData() *ApplicationData {
 	defaultTools := []Tool{
 		{Name: toolGoogleSearch, Enabled: true},
 		{Name: toolURLContext, Enabled: true},
+		{Name: toolCodeExecution, Enabled: false},
+	}
+
+	defaultCompaction := Compaction{
+		Threshold: 100_000,
+		KeepTurns: 4,
+	}
+
+	defaultRetry := Retry{
+		MaxRetries:   gemini.DefaultRetryPolicy.MaxRetries,
+		InitialDelay: gemini.DefaultRetryPolicy.InitialDelay.Seconds(),
+		MaxDelay:     gemini.DefaultRetryPolicy.MaxDelay.Seconds(),
+		Multiplier:   gemini.DefaultRetryPolicy.Multiplier,
+		Jitter:       gemini.DefaultRetryPolicy.Jitter,
 	}
 
 	return &ApplicationData{
-		SystemPrompts:  make(map[string]gemini.SystemInstruction),
-		SafetySettings: defaultSafetySettings,
-		Tools:          defaultTools,
-		History:        make(map[string][]*gemini.SerializableContent),
+		Provider:                  Provider{Type: ProviderGemini},
+		SystemPrompts:             make(map[string]gemini.SystemInstruction),
+		SafetySettings:            defaultSafetySettings,
+		Tools:                     defaultTools,
+		MaxFunctionCalls:          gemini.DefaultMaxFunctionCalls,
+		MCPServers:                make(map[string]MCPServer),
+		GenerationConfigOverrides: make(map[string]gemini.GenerationConfig),
+		Pricing:                   make(map[string]gemini.Pricing),
+		Compaction:                defaultCompaction,
+		Retry:                     defaultRetry,
+		Schemas:                   make(map[string]map[string]any),
 	}
 }
 
-// AddHistoryRecord adds a history record to the application data.
-func (d *ApplicationData) AddHistoryRecord(label string, content []*genai.Content) {
-	serializableContent := make([]*gemini.SerializableContent, len(content))
-	for i, c := range content {
-		serializableContent[i] = gemini.NewSerializableContent(c)
+// SystemPromptGenerationConfig returns the generation parameters for the system
+// prompt, where the system prompt specific parameters take precedence over the
+// default ones.
+func (d *ApplicationData) SystemPromptGenerationConfig(systemPrompt string) *gemini.GenerationConfig {
+	override, ok := d.GenerationConfigOverrides[systemPrompt]
+	if !ok {
+		return d.GenerationConfig.Merge(nil)
 	}
+	return d.GenerationConfig.Merge(&override)
+}
 
-	d.History[label] = serializableContent
+// SystemPromptLabel returns the label of the configured system prompt with
+// the text of the system instruction, or an empty string if there is none.
+func (d *ApplicationData) SystemPromptLabel(systemInstruction *genai.Content) string {
+	if systemInstruction == nil {
+		return ""
+	}
+
+	var text strings.Builder
+	for _, part := range systemInstruction.Parts {
+		text.WriteString(part.Text)
+	}
+	for label, systemPrompt := range d.SystemPrompts {
+		if string(systemPrompt) == text.String() {
+			return label
+		}
+	}
+	return ""
 }
 
 // GenaiSafetySettings converts the application data safety settings to genai safety settings.
@@ -96,18 +260,32 @@ func (d *ApplicationData) GenaiSafetySettings() []*genai.SafetySetting {
 	for i, s := range d.SafetySettings {
 		genaiSafetySettings[i] = &genai.SafetySetting{
 			Category:  s.Category,
-			Threshold: s.Threshold.toGenai(),
+			Threshold: s.Threshold.Genai(),
 		}
 	}
 
 	return genaiSafetySettings
 }
 
+// NewSafetySettings converts the genai safety settings to the application
+// data safety settings.
+func NewSafetySettings(settings []*genai.SafetySetting) []SafetySetting {
+	safetySettings := make([]SafetySetting, len(settings))
+	for i, s := range settings {
+		safetySettings[i] = SafetySetting{
+			Category:  s.Category,
+			Threshold: NewThreshold(s.Threshold),
+		}
+	}
+
+	return safetySettings
+}
+
 // GenaiTools builds a genai Tool slice using enabled entries.
 func (d *ApplicationData) GenaiTools() []*genai.Tool {
 	tools := make([]*genai.Tool, 0, len(d.Tools))
 	for _, tool := range d.Tools {
-		if !tool.Enabled {
+		if !tool.Enabled || tool.isFunction() {
 			continue
 		}
 
@@ -117,6 +295,8 @@ func (d *ApplicationData) GenaiTools() []*genai.Tool {
 			genaiTool = &genai.Tool{GoogleSearch: &genai.GoogleSearch{}}
 		case toolURLContext:
 			genaiTool = &genai.Tool{URLContext: &genai.URLContext{}}
+		case toolCodeExecution:
+			genaiTool = &genai.Tool{CodeExecution: &genai.ToolCodeExecution{}}
 		default:
 			continue // Skip unknown tools
 		}
@@ -127,6 +307,68 @@ func (d *ApplicationData) GenaiTools() []*genai.Tool {
 	return tools
 }
 
+// Functions builds the functions the model can call using enabled function tools.
+func (d *ApplicationData) Functions() ([]gemini.Function, error) {
+	functions := make([]gemini.Function, 0, len(d.Tools))
+	for _, tool := range d.Tools {
+		if !tool.Enabled || !tool.isFunction() {
+			continue
+		}
+
+		declaration := &genai.FunctionDeclaration{
+			Name:        tool.Name,
+			Description: tool.Description,
+		}
+		if tool.Parameters != nil {
+			declaration.ParametersJsonSchema = tool.Parameters
+		}
+
+		function, err := gemini.NewCommandFunction(declaration, tool.Command)
+		if err != nil {
+			return nil, err
+		}
+		functions = append(functions, function)
+	}
+
+	return functions, nil
+}
+
+// ApprovedFunctions returns the names of enabled function tools, which are
+// called without confirmation.
+func (d *ApplicationData) ApprovedFunctions() map[string]bool {
+	approved := make(map[string]bool)
+	for _, tool := range d.Tools {
+		if tool.Enabled && tool.isFunction() && tool.AutoApprove {
+			approved[tool.Name] = true
+		}
+	}
+
+	return approved
+}
+
+// ResponseSchema returns the named schema from the schema registry. If there
+// is no such schema, the name is treated as a path to a JSON schema file.
+func (d *ApplicationData) ResponseSchema(name string) (map[string]any, error) {
+	if schema, ok := d.Schemas[name]; ok {
+		return schema, nil
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("schema %q not found", name)
+		}
+		return nil, fmt.Errorf("error reading schema file: %w", err)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("error decoding schema file: %w", err)
+	}
+
+	return schema, nil
+}
+
 // GenaiContentConfig builds a genai GenerateContentConfig with the current
 // safety settings and enabled tools.
 func (d *ApplicationData) GenaiContentConfig() *genai.GenerateContentConfig {
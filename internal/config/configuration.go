@@ -57,6 +57,11 @@ func (c *Configuration) Flush() error {
 		return err
 	}
 
+	return c.write()
+}
+
+// write serializes and writes the configuration to the file.
+func (c *Configuration) write() error {
 	// Create the file if it does not exist.
 	file, err := os.Create(c.filePath)
 	if err != nil {
@@ -88,6 +93,18 @@ func (c *Configuration) Flush() error {
 	return nil
 }
 
+// Update applies the changes to the application data and flushes the
+// configuration. The changes are applied after merging the on-disk data,
+// so that they take precedence over the modifications made to the file.
+func (c *Configuration) Update(update func(data *ApplicationData)) error {
+	if err := c.reloadIfStale(); err != nil {
+		return err
+	}
+	update(c.Data)
+
+	return c.write()
+}
+
 // reloadIfStale re-reads and merges the on-disk configuration if the file was
 // modified since the last load/flush.
 func (c *Configuration) reloadIfStale() error {
@@ -128,10 +145,20 @@ func (c *Configuration) mergeApplicationData(onDisk *ApplicationData) {
 		return
 	}
 
-	// The CLI never modifies these fields; always overwrite with the on-disk values.
+	// The CLI modifies these fields only using Update, after merging the on-disk
+	// data; always overwrite with the on-disk values.
+	c.Data.Provider = onDisk.Provider
 	c.Data.SystemPrompts = onDisk.SystemPrompts
 	c.Data.SafetySettings = onDisk.SafetySettings
 	c.Data.Tools = onDisk.Tools
+	c.Data.MaxFunctionCalls = onDisk.MaxFunctionCalls
+	c.Data.MCPServers = onDisk.MCPServers
+	c.Data.GenerationConfig = onDisk.GenerationConfig
+	c.Data.GenerationConfigOverrides = onDisk.GenerationConfigOverrides
+	c.Data.Pricing = onDisk.Pricing
+	c.Data.Compaction = onDisk.Compaction
+	c.Data.Retry = onDisk.Retry
+	c.Data.Schemas = onDisk.Schemas
 
 	// Merge history records.
 	if onDisk.History != nil {
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// apiKeyCommandTimeout is the maximum time the API key command may take.
+const apiKeyCommandTimeout = 30 * time.Second
+
+// The supported generative model provider types.
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+	ProviderOllama = "ollama"
+)
+
+// ProviderTypes lists the supported generative model provider types.
+var ProviderTypes = []string{ProviderGemini, ProviderOpenAI, ProviderOllama}
+
+// The backends of the gemini provider.
+const (
+	BackendGeminiAPI = "gemini_api"
+	BackendVertexAI  = "vertex_ai"
+)
+
+// Provider represents the generative model provider configuration.
+type Provider struct {
+	// The provider type: gemini (default), openai or ollama.
+	Type string `json:"type"`
+	// The backend of the gemini provider: gemini_api (default) or vertex_ai.
+	Backend string `json:"backend,omitempty"`
+	// The Google Cloud project and location of the Vertex AI backend.
+	Project  string `json:"project,omitempty"`
+	Location string `json:"location,omitempty"`
+	// The base URL of the provider API, overriding the default one.
+	BaseURL string `json:"base_url,omitempty"`
+	// The API version of the gemini provider, overriding the default one.
+	APIVersion string `json:"api_version,omitempty"`
+	// The name of the environment variable containing the API key.
+	APIKeyEnv string `json:"api_key_env,omitempty"`
+	// The path to the file containing the API key.
+	APIKeyFile string `json:"api_key_file,omitempty"`
+	// The command writing the API key to the standard output,
+	// e.g., a password manager CLI.
+	APIKeyCommand []string `json:"api_key_command,omitempty"`
+	// The path to the Google Cloud credentials file of the Vertex AI backend.
+	// If not set, the application default credentials are used.
+	CredentialsFile string `json:"credentials_file,omitempty"`
+	// The default generative model.
+	Model string `json:"model,omitempty"`
+}
+
+// Override returns a copy of the provider configuration, with the non-empty
+// fields of the other configuration taking precedence. If the other provider
+// type differs, the configured settings are discarded, as they apply to the
+// configured provider only.
+func (p Provider) Override(other Provider) Provider {
+	if other.Type != "" && other.Type != p.Type {
+		p = Provider{Type: other.Type}
+	}
+
+	override := func(value *string, otherValue string) {
+		if otherValue != "" {
+			*value = otherValue
+		}
+	}
+	override(&p.Backend, other.Backend)
+	override(&p.Project, other.Project)
+	override(&p.Location, other.Location)
+	override(&p.BaseURL, other.BaseURL)
+	override(&p.APIVersion, other.APIVersion)
+	override(&p.CredentialsFile, other.CredentialsFile)
+	override(&p.Model, other.Model)
+	if other.APIKeyEnv != "" || other.APIKeyFile != "" || len(other.APIKeyCommand) > 0 {
+		p.APIKeyEnv, p.APIKeyFile, p.APIKeyCommand = other.APIKeyEnv, other.APIKeyFile, other.APIKeyCommand
+	}
+
+	return p
+}
+
+// APIKey returns the API key read using the configured source: the command,
+// the file, or the environment variable, in order of precedence. An empty
+// string is returned if no source is configured.
+func (p *Provider) APIKey(ctx context.Context) (string, error) {
+	var (
+		apiKey string
+		err    error
+	)
+	switch {
+	case len(p.APIKeyCommand) > 0:
+		apiKey, err = runAPIKeyCommand(ctx, p.APIKeyCommand)
+	case p.APIKeyFile != "":
+		var data []byte
+		data, err = os.ReadFile(p.APIKeyFile)
+		if err != nil {
+			err = fmt.Errorf("error reading API key file: %w", err)
+		}
+		apiKey = string(data)
+	case p.APIKeyEnv != "":
+		apiKey = os.Getenv(p.APIKeyEnv)
+		if apiKey == "" {
+			err = fmt.Errorf("environment variable %s is not set", p.APIKeyEnv)
+		}
+	default:
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" {
+		return "", errors.New("the configured API key is empty")
+	}
+	return apiKey, nil
+}
+
+// runAPIKeyCommand runs the command and returns its standard output.
+func runAPIKeyCommand(ctx context.Context, command []string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, apiKeyCommandTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...) //nolint:gosec // the command is user-configured
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("API key command failed: %w: %s", err, message)
+		}
+		return "", fmt.Errorf("API key command failed: %w", err)
+	}
+
+	return stdout.String(), nil
+}
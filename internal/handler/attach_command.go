@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	"github.com/reugn/gemini-cli/gemini"
+)
+
+// attachmentPrefix is the prefix of an inline file attachment in the chat message,
+// e.g., @path/to/file.
+const attachmentPrefix = "@"
+
+var attachOptions = []string{
+	"List attached files",
+	"Clear attached files",
+}
+
+// Attachments holds the files attached to the next chat message.
+// The zero value is ready to use.
+type Attachments struct {
+	files []*gemini.Attachment
+}
+
+// Add adds the files to the attachments.
+func (a *Attachments) Add(files ...*gemini.Attachment) {
+	a.files = append(a.files, files...)
+}
+
+// Files returns a copy of the attached files slice.
+func (a *Attachments) Files() []*gemini.Attachment {
+	return slices.Clone(a.files)
+}
+
+// Clear removes all the attached files.
+func (a *Attachments) Clear() {
+	a.files = nil
+}
+
+// AttachCommand processes the file attachment system command.
+// It implements the MessageHandler interface.
+type AttachCommand struct {
+	*IO
+	attachments *Attachments
+}
+
+var _ MessageHandler = (*AttachCommand)(nil)
+
+// NewAttachCommand returns a new AttachCommand.
+func NewAttachCommand(io *IO, attachments *Attachments) *AttachCommand {
+	return &AttachCommand{
+		IO:          io,
+		attachments: attachments,
+	}
+}
+
+// Handle processes the file attachment system command.
+// The space-separated file paths are attached to the next chat message.
+// If no paths are provided, an attachment operation is prompted.
+func (h *AttachCommand) Handle(message string) (Response, bool) {
+	paths := strings.Fields(message)
+	if len(paths) > 0 {
+		return h.handleAttach(paths), false
+	}
+
+	option, err := h.selectAttachOption()
+	if err != nil {
+		return newErrorResponse(err), false
+	}
+
+	var response Response
+	switch option {
+	case attachOptions[0]:
+		response = h.handleList()
+	case attachOptions[1]:
+		response = h.handleClear()
+	default:
+		response = newErrorResponse(fmt.Errorf("unsupported option: %s", option))
+	}
+
+	return response, false
+}
+
+// handleAttach handles the file attachment request.
+func (h *AttachCommand) handleAttach(paths []string) Response {
+	files := make([]*gemini.Attachment, len(paths))
+	for i, path := range paths {
+		attachment, err := gemini.NewAttachment(path)
+		if err != nil {
+			return newErrorResponse(err)
+		}
+		files[i] = attachment
+	}
+	if err := gemini.CheckAttachmentSize(append(h.attachments.Files(), files...)); err != nil {
+		return newErrorResponse(err)
+	}
+
+	h.attachments.Add(files...)
+
+	return dataResponse(fmt.Sprintf("Attached %d file(s) to the next message.", len(files)))
+}
+
+// handleList handles the attached files list request.
+func (h *AttachCommand) handleList() Response {
+	h.terminal.Write(h.terminalPrompt)
+	files := h.attachments.Files()
+	if len(files) == 0 {
+		return dataResponse("No files attached.")
+	}
+
+	var b strings.Builder
+	for _, file := range files {
+		fmt.Fprintf(&b, "\n* %s", file)
+	}
+
+	return dataResponse(fmt.Sprintf("Attached files:%s", b.String()))
+}
+
+// handleClear handles the attached files clear request.
+func (h *AttachCommand) handleClear() Response {
+	h.terminal.Write(h.terminalPrompt)
+	h.attachments.Clear()
+	return dataResponse("Removed the attached files.")
+}
+
+// selectAttachOption returns the selected attachment action name.
+func (h *AttachCommand) selectAttachOption() (string, error) {
+	prompt := promptui.Select{
+		Label:        "Select attachment option",
+		HideSelected: true,
+		Items:        attachOptions,
+	}
+
+	_, result, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+
+	return result, nil
+}
+
+// parseInlineAttachments returns the files referenced in the message using the
+// inline attachment syntax (e.g., @path/to/file). Words that do not refer to
+// an existing file are considered plain text.
+func parseInlineAttachments(message string) ([]*gemini.Attachment, error) {
+	var files []*gemini.Attachment
+	for _, word := range strings.Fields(message) {
+		path, ok := strings.CutPrefix(word, attachmentPrefix)
+		if !ok || path == "" {
+			continue
+		}
+
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		attachment, err := gemini.NewAttachment(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, attachment)
+	}
+
+	return files, nil
+}
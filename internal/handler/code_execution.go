@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// formatExecutableCode returns the labelled markdown section containing
+// the code generated by the model for execution.
+func formatExecutableCode(code *genai.ExecutableCode) string {
+	language := ""
+	if code.Language != genai.LanguageUnspecified {
+		language = strings.ToLower(string(code.Language))
+	}
+	return "\n\n**Executed code**\n" + codeBlock(language, code.Code) + "\n\n"
+}
+
+// formatCodeExecutionResult returns the labelled markdown section containing
+// the outcome and the output of the code execution.
+func formatCodeExecutionResult(result *genai.CodeExecutionResult) string {
+	section := fmt.Sprintf("\n\n**Execution result:** %s\n", executionOutcome(result.Outcome))
+	if strings.TrimSpace(result.Output) != "" {
+		section += codeBlock("", result.Output)
+	}
+	return section + "\n\n"
+}
+
+// executionOutcome returns the description of the code execution outcome.
+func executionOutcome(outcome genai.Outcome) string {
+	switch outcome {
+	case genai.OutcomeOK:
+		return "succeeded"
+	case genai.OutcomeFailed:
+		return "failed"
+	case genai.OutcomeDeadlineExceeded:
+		return "timed out"
+	default:
+		return "unknown"
+	}
+}
+
+// codeBlock returns the fenced markdown code block. The fence is longer than
+// any backtick sequence in the code, so that it cannot be closed prematurely.
+func codeBlock(language, code string) string {
+	longest, current := 0, 0
+	for _, r := range code {
+		if r == '`' {
+			current++
+			longest = max(longest, current)
+		} else {
+			current = 0
+		}
+	}
+
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + language + "\n" + strings.TrimRight(code, "\n") + "\n" + fence
+}
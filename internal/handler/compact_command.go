@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/reugn/gemini-cli/gemini"
+)
+
+// CompactCommand processes the chat history compaction system command.
+// It implements the MessageHandler interface.
+type CompactCommand struct {
+	*IO
+	session      *gemini.ChatSession
+	queryOptions *QueryOptions
+}
+
+var _ MessageHandler = (*CompactCommand)(nil)
+
+// NewCompactCommand returns a new CompactCommand.
+func NewCompactCommand(io *IO, session *gemini.ChatSession,
+	queryOptions *QueryOptions) *CompactCommand {
+	return &CompactCommand{
+		IO:           io,
+		session:      session,
+		queryOptions: queryOptions,
+	}
+}
+
+// Handle processes the chat history compaction system command.
+func (h *CompactCommand) Handle(_ string) (Response, bool) {
+	ctx, cancel := newRequestContext()
+	defer cancel()
+
+	h.terminal.Spinner.Start()
+	defer h.terminal.Spinner.Stop()
+
+	result, err := compactHistory(ctx, h.session, h.queryOptions.Compaction.KeepTurns)
+	if err != nil {
+		return requestErrorResponse(err), false
+	}
+
+	return dataResponse(result), false
+}
+
+// compactHistory replaces the chat turns preceding the last keepTurns turns
+// with a model-generated summary. It returns a message describing the change
+// of the context size.
+func compactHistory(ctx context.Context, session *gemini.ChatSession, keepTurns int) (string, error) {
+	if session.Turns() <= keepTurns {
+		return fmt.Sprintf("Nothing to compact: the chat history has no more than %d turns.",
+			keepTurns), nil
+	}
+
+	before, err := session.CountTokens(ctx, "")
+	if err != nil {
+		return "", err
+	}
+
+	summarized, err := session.SummarizeHistory(ctx, keepTurns)
+	if err != nil {
+		return "", err
+	}
+
+	after, err := session.CountTokens(ctx, "")
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Compacted %d chat turns: %d -> %d tokens.",
+		summarized, before.Tokens, after.Tokens), nil
+}
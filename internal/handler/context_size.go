@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/manifoldco/promptui"
+	"github.com/reugn/gemini-cli/gemini"
+	"github.com/reugn/gemini-cli/internal/terminal/color"
+)
+
+const (
+	// contextWarningRatio is the fraction of the model input token limit,
+	// above which the user is warned before sending a message.
+	contextWarningRatio = 0.9
+	// contextTrimRatio is the fraction of the model input token limit
+	// the chat context is trimmed to.
+	contextTrimRatio = 0.75
+	// contextEstimateRatio is the fraction of the context size threshold,
+	// above which the estimated context size is verified by counting tokens.
+	contextEstimateRatio = 0.8
+)
+
+var contextOptions = []string{
+	"Send the message",
+	"Trim the oldest turns",
+	"Summarize the earlier turns",
+	"Clear the chat history",
+	"Cancel",
+}
+
+// checkContextSize estimates the size of the chat context including the pending
+// message from the usage of the last request, and counts the tokens only if the
+// estimate is close to the context size threshold. If the automatic compaction is enabled and the context exceeds the
+// threshold, the chat history is compacted. If the context approaches the model
+// input token limit, the user is prompted to reduce the chat history.
+// It returns false along with a response if the message should not be sent.
+func (h *GeminiQuery) checkContextSize(message string,
+	attachments []*gemini.Attachment) (Response, bool) {
+	ctx, cancel := newRequestContext()
+	defer cancel()
+
+	h.terminal.Spinner.Start()
+	size, err := h.contextSize(ctx, message, attachments)
+	h.terminal.Spinner.Stop()
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return requestErrorResponse(err), false
+		}
+		// the token count is advisory; let the request itself fail if it must
+		return nil, true
+	}
+	if size == nil {
+		return nil, true
+	}
+	if compaction := h.opts.Compaction; compaction.Enabled && compaction.Threshold > 0 &&
+		size.Tokens > compaction.Threshold {
+		size = h.autoCompactHistory(ctx, size, message, attachments)
+	}
+	if size.Ratio() < contextWarningRatio {
+		return nil, true
+	}
+
+	defer h.terminal.Write(h.terminalPrompt)
+	h.terminal.Write(color.Yellow(fmt.Sprintf("The message and the chat context take %s.\n",
+		formatContextSize(size))))
+	option, err := h.selectContextOption()
+	if err != nil {
+		return newErrorResponse(err), false
+	}
+
+	switch option {
+	case contextOptions[0]:
+		return nil, true
+	case contextOptions[1]:
+		return h.trimHistory(ctx, size, message, attachments)
+	case contextOptions[2]:
+		return h.summarizeHistory(ctx)
+	case contextOptions[3]:
+		if err := h.session.ClearHistory(); err != nil {
+			return newErrorResponse(err), false
+		}
+		h.terminal.Write("Cleared the chat history.\n")
+		return nil, true
+	default:
+		return dataResponse(canceledMessage), false
+	}
+}
+
+// contextSize counts the tokens of the chat context including the pending
+// message. It returns nil if the estimated context size is well below the
+// threshold, to avoid the token counting request.
+func (h *GeminiQuery) contextSize(ctx context.Context, message string,
+	attachments []*gemini.Attachment) (*gemini.ContextSize, error) {
+	if estimate, ok := h.session.EstimateTokens(message, attachments...); ok {
+		limit, err := h.session.InputTokenLimit(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !h.nearContextThreshold(estimate, limit) {
+			return nil, nil
+		}
+	}
+	return h.session.CountTokens(ctx, message, attachments...)
+}
+
+// nearContextThreshold reports whether the number of tokens is close to
+// the warning ratio of the model input token limit, or to the automatic
+// compaction threshold, whichever is lower.
+func (h *GeminiQuery) nearContextThreshold(tokens, limit int32) bool {
+	var threshold float64
+	if limit > 0 {
+		threshold = float64(limit) * contextWarningRatio
+	}
+	if compaction := h.opts.Compaction; compaction.Enabled && compaction.Threshold > 0 &&
+		(threshold == 0 || float64(compaction.Threshold) < threshold) {
+		threshold = float64(compaction.Threshold)
+	}
+	if threshold == 0 {
+		return false
+	}
+	return float64(tokens) >= threshold*contextEstimateRatio
+}
+
+// trimHistory removes the oldest chat turns until the context fits into
+// the trim ratio of the model input token limit.
+func (h *GeminiQuery) trimHistory(ctx context.Context, size *gemini.ContextSize,
+	message string, attachments []*gemini.Attachment) (Response, bool) {
+	h.terminal.Spinner.Start()
+	maxTokens := int32(float64(size.InputTokenLimit) * contextTrimRatio)
+	removed, err := h.session.TrimHistory(ctx, maxTokens, message, attachments...)
+	h.terminal.Spinner.Stop()
+	if err != nil {
+		return requestErrorResponse(err), false
+	}
+
+	h.terminal.Write(fmt.Sprintf("Removed %d of the oldest chat turns.\n", removed))
+	return nil, true
+}
+
+// summarizeHistory replaces the earlier chat turns with a model-generated summary.
+// The message is not sent, so that the user can review the resulting context size.
+func (h *GeminiQuery) summarizeHistory(ctx context.Context) (Response, bool) {
+	h.terminal.Spinner.Start()
+	result, err := compactHistory(ctx, h.session, h.opts.Compaction.KeepTurns)
+	h.terminal.Spinner.Stop()
+	if err != nil {
+		return requestErrorResponse(err), false
+	}
+
+	return dataResponse(result + " Send the message again to continue."), false
+}
+
+// autoCompactHistory compacts the chat history and returns the resulting
+// context size. The size is returned unchanged if there are no turns to
+// summarize. Compaction errors are reported, but do not prevent the message
+// from being sent.
+func (h *GeminiQuery) autoCompactHistory(ctx context.Context, size *gemini.ContextSize,
+	message string, attachments []*gemini.Attachment) *gemini.ContextSize {
+	if h.session.Turns() <= h.opts.Compaction.KeepTurns {
+		return size
+	}
+
+	h.terminal.Spinner.Start()
+	result, err := compactHistory(ctx, h.session, h.opts.Compaction.KeepTurns)
+	if err != nil {
+		h.terminal.Spinner.Stop()
+		h.terminal.Write(newErrorResponse(err).String())
+		return size
+	}
+
+	compacted, err := h.session.CountTokens(ctx, message, attachments...)
+	h.terminal.Spinner.Stop()
+	if err != nil {
+		return size
+	}
+
+	h.terminal.Write(color.Dim(result) + "\n")
+	return compacted
+}
+
+// selectContextOption returns the selected context reduction option.
+func (h *GeminiQuery) selectContextOption() (string, error) {
+	prompt := promptui.Select{
+		Label:        "The model input token limit is nearly reached",
+		HideSelected: true,
+		Items:        contextOptions,
+	}
+
+	_, result, err := prompt.Run()
+	return result, err
+}
+
+// formatContextSize returns the string representation of the context size
+// relative to the model input token limit.
+func formatContextSize(size *gemini.ContextSize) string {
+	if size.InputTokenLimit <= 0 {
+		return fmt.Sprintf("%d tokens", size.Tokens)
+	}
+	return fmt.Sprintf("%d of %d tokens (%.1f%%)", size.Tokens, size.InputTokenLimit,
+		100*size.Ratio())
+}
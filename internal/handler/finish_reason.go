@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	"github.com/reugn/gemini-cli/gemini"
+	"github.com/reugn/gemini-cli/internal/config"
+	"github.com/reugn/gemini-cli/internal/terminal/color"
+	"google.golang.org/genai"
+)
+
+// continuePrompt is the message sent to continue a truncated answer.
+const continuePrompt = "Continue your previous answer exactly from where it stopped."
+
+var (
+	blockedOptions = []string{
+		"Retry with relaxed safety thresholds",
+		"Cancel",
+	}
+	truncatedOptions = []string{
+		"Continue the answer",
+		"Cancel",
+	}
+)
+
+// responseIssue describes why the model response is blocked or incomplete.
+type responseIssue struct {
+	// The explanation of the issue.
+	message string
+	// Whether the prompt or the response is blocked, so that the message
+	// has to be sent again.
+	blocked bool
+	// Whether the answer is truncated and can be continued.
+	truncated bool
+	// The harm categories that caused the block, whose thresholds can be relaxed.
+	categories []genai.HarmCategory
+}
+
+// promptIssue returns the issue if the request failed because the prompt
+// was blocked; otherwise, it returns nil.
+func promptIssue(err error, settings []*genai.SafetySetting) *responseIssue {
+	var blockedErr *gemini.PromptBlockedError
+	if !errors.As(err, &blockedErr) {
+		return nil
+	}
+
+	feedback := blockedErr.Feedback
+	issue := &responseIssue{
+		message: fmt.Sprintf("The prompt was blocked (%s).", feedback.BlockReason),
+		blocked: true,
+	}
+	if feedback.BlockReasonMessage != "" {
+		issue.message += " " + feedback.BlockReasonMessage
+	}
+	if feedback.BlockReason == genai.BlockedReasonSafety {
+		issue.explainRatings(feedback.SafetyRatings, settings)
+	}
+
+	return issue
+}
+
+// candidateIssue returns the issue if the response candidate finished for
+// a reason other than a natural stop; otherwise, it returns nil.
+//
+//nolint:gocyclo
+func candidateIssue(candidate *genai.Candidate, settings []*genai.SafetySetting) *responseIssue {
+	if candidate == nil {
+		return nil
+	}
+
+	issue := &responseIssue{blocked: true}
+	switch candidate.FinishReason {
+	case "", genai.FinishReasonUnspecified, genai.FinishReasonStop:
+		return nil
+	case genai.FinishReasonMaxTokens:
+		issue.message = "The answer was truncated at the maximum number of output tokens."
+		issue.blocked = false
+		issue.truncated = true
+	case genai.FinishReasonSafety:
+		issue.message = "The response was blocked by the safety filters."
+		issue.explainRatings(candidate.SafetyRatings, settings)
+	case genai.FinishReasonRecitation:
+		issue.message = "The response was stopped, as it resembled existing content, " +
+			"such as copyrighted material. Try rephrasing the message."
+	case genai.FinishReasonBlocklist, genai.FinishReasonProhibitedContent,
+		genai.FinishReasonImageProhibitedContent:
+		issue.message = "The response was blocked, as it contained prohibited content."
+	case genai.FinishReasonSPII:
+		issue.message = "The response was blocked, as it contained sensitive personal information."
+	case genai.FinishReasonImageSafety:
+		issue.message = "The generated image was blocked by the safety filters."
+	case genai.FinishReasonMalformedFunctionCall, genai.FinishReasonUnexpectedToolCall:
+		issue.message = "The model generated an invalid function call."
+	case genai.FinishReasonLanguage:
+		issue.message = "The response was stopped, as the language is not supported."
+	default:
+		issue.message = fmt.Sprintf("The response was stopped (%s).", candidate.FinishReason)
+	}
+	if candidate.FinishMessage != "" {
+		issue.message += " " + candidate.FinishMessage
+	}
+
+	return issue
+}
+
+// explainRatings appends the safety ratings that caused the block to the
+// issue message, along with the configured thresholds, and records their
+// categories if the thresholds can be relaxed.
+func (i *responseIssue) explainRatings(ratings []*genai.SafetyRating, settings []*genai.SafetySetting) {
+	var lines []string
+	for _, rating := range ratings {
+		threshold := safetyThreshold(settings, rating.Category)
+		if !rating.Blocked && !exceedsThreshold(rating.Probability, threshold) {
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("  %s: probability %s, threshold %s",
+			categoryName(rating.Category), rating.Probability, thresholdName(threshold)))
+		if _, ok := relaxThreshold(threshold); ok {
+			i.categories = append(i.categories, rating.Category)
+		}
+	}
+	if len(lines) > 0 {
+		i.message += "\n" + strings.Join(lines, "\n")
+	}
+}
+
+// resolveIssue writes the explanation of the issue, and offers to retry the
+// message with relaxed safety thresholds, or to continue the truncated answer.
+func (h *GeminiQuery) resolveIssue(message string, issue *responseIssue) Response {
+	var options []string
+	switch {
+	case len(issue.categories) > 0:
+		options = blockedOptions
+	case issue.truncated:
+		options = truncatedOptions
+	default:
+		return dataResponse(color.Yellow(issue.message))
+	}
+
+	h.terminal.Write(color.Yellow(issue.message) + "\n")
+	prompt := promptui.Select{
+		Label:        "Select an action",
+		HideSelected: true,
+		Items:        options,
+	}
+	_, option, err := prompt.Run()
+	h.terminal.Write(h.terminalPrompt)
+	if err != nil || option == options[len(options)-1] {
+		return dataResponse(canceledMessage)
+	}
+
+	if issue.truncated {
+		response, _ := h.Handle(continuePrompt)
+		return response
+	}
+
+	h.terminal.Write(color.Dim(h.relaxSafetySettings(issue.categories)) + "\n")
+	response, _ := h.Handle(message)
+	return response
+}
+
+// relaxSafetySettings lowers the blocking thresholds of the categories by one
+// level for the rest of the session. It returns the description of the change.
+func (h *GeminiQuery) relaxSafetySettings(categories []genai.HarmCategory) string {
+	settings := h.session.SafetySettings()
+	var changes []string
+	for _, category := range categories {
+		threshold := safetyThreshold(settings, category)
+		relaxed, ok := relaxThreshold(threshold)
+		if !ok {
+			continue
+		}
+
+		settings = setSafetyThreshold(settings, category, relaxed)
+		changes = append(changes, fmt.Sprintf("%s %s -> %s", categoryName(category),
+			thresholdName(threshold), thresholdName(relaxed)))
+	}
+	_ = h.session.SetSafetySettings(settings)
+
+	return "Relaxed the safety thresholds for this session: " + strings.Join(changes, ", ")
+}
+
+// safetyThreshold returns the configured blocking threshold of the category.
+func safetyThreshold(settings []*genai.SafetySetting, category genai.HarmCategory) genai.HarmBlockThreshold {
+	for _, setting := range settings {
+		if setting.Category == category {
+			return setting.Threshold
+		}
+	}
+	return genai.HarmBlockThresholdUnspecified
+}
+
+// relaxThreshold returns the threshold one level less strict than the given
+// one. The unspecified threshold is treated as the model default of blocking
+// medium and above. It returns false if blocking is already disabled.
+func relaxThreshold(threshold genai.HarmBlockThreshold) (genai.HarmBlockThreshold, bool) {
+	switch threshold {
+	case genai.HarmBlockThresholdBlockLowAndAbove:
+		return genai.HarmBlockThresholdBlockMediumAndAbove, true
+	case genai.HarmBlockThresholdBlockMediumAndAbove, genai.HarmBlockThresholdUnspecified, "":
+		return genai.HarmBlockThresholdBlockOnlyHigh, true
+	case genai.HarmBlockThresholdBlockOnlyHigh:
+		return genai.HarmBlockThresholdOff, true
+	default:
+		return threshold, false
+	}
+}
+
+// exceedsThreshold returns true if the harm probability is blocked by the
+// threshold.
+func exceedsThreshold(probability genai.HarmProbability, threshold genai.HarmBlockThreshold) bool {
+	switch threshold {
+	case genai.HarmBlockThresholdBlockLowAndAbove:
+		return probability == genai.HarmProbabilityLow || probability == genai.HarmProbabilityMedium ||
+			probability == genai.HarmProbabilityHigh
+	case genai.HarmBlockThresholdBlockMediumAndAbove, genai.HarmBlockThresholdUnspecified, "":
+		return probability == genai.HarmProbabilityMedium || probability == genai.HarmProbabilityHigh
+	case genai.HarmBlockThresholdBlockOnlyHigh:
+		return probability == genai.HarmProbabilityHigh
+	default:
+		return false
+	}
+}
+
+// categoryName returns the readable name of the harm category.
+func categoryName(category genai.HarmCategory) string {
+	name := strings.TrimPrefix(string(category), "HARM_CATEGORY_")
+	return strings.ToLower(strings.ReplaceAll(name, "_", " "))
+}
+
+// thresholdName returns the name of the blocking threshold, as used in the
+// safety settings of the configuration file.
+func thresholdName(threshold genai.HarmBlockThreshold) string {
+	if name := config.NewThreshold(threshold); name != "" {
+		return string(name)
+	}
+	return "default"
+}
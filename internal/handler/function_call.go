@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/manifoldco/promptui"
+	"github.com/reugn/gemini-cli/internal/terminal/color"
+	"google.golang.org/genai"
+)
+
+const maxFunctionCallLength = 200
+
+var functionCallOptions = []string{
+	"Allow",
+	"Always allow in this session",
+	"Decline",
+}
+
+// confirmFunctionCall writes the function call requested by the model, and
+// prompts the user to approve it unless the function has been approved for
+// the session.
+func (h *GeminiQuery) confirmFunctionCall(call *genai.FunctionCall) bool {
+	h.terminal.Spinner.Stop()
+	defer h.terminal.Spinner.Start()
+
+	h.terminal.Write(color.Dim(formatFunctionCall(call)) + "\n")
+	if h.opts.ApprovedFunctions[call.Name] {
+		return true
+	}
+
+	prompt := promptui.Select{
+		Label:        fmt.Sprintf("Allow calling %s", call.Name),
+		HideSelected: true,
+		Items:        functionCallOptions,
+	}
+
+	i, _, err := prompt.Run()
+	switch {
+	case err != nil || functionCallOptions[i] == functionCallOptions[2]:
+		h.terminal.Write(color.Dim("Declined the function call.") + "\n")
+		return false
+	case functionCallOptions[i] == functionCallOptions[1]:
+		if h.opts.ApprovedFunctions == nil {
+			h.opts.ApprovedFunctions = make(map[string]bool)
+		}
+		h.opts.ApprovedFunctions[call.Name] = true
+	}
+
+	return true
+}
+
+// formatFunctionCall returns the string representation of the function call,
+// with the arguments encoded in JSON and truncated if too long.
+func formatFunctionCall(call *genai.FunctionCall) string {
+	args, err := json.Marshal(call.Args)
+	if err != nil || call.Args == nil {
+		args = []byte("{}")
+	}
+
+	formatted := fmt.Sprintf("-> %s(%s)", call.Name, args)
+	if runes := []rune(formatted); len(runes) > maxFunctionCallLength {
+		formatted = string(runes[:maxFunctionCallLength]) + "..."
+	}
+	return formatted
+}
@@ -1,58 +1,329 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/charmbracelet/glamour"
 	"github.com/reugn/gemini-cli/gemini"
+	"google.golang.org/genai"
 )
 
+const canceledMessage = "The request has been canceled."
+
 // GeminiQuery processes queries to gemini models.
 // It implements the MessageHandler interface.
 type GeminiQuery struct {
 	*IO
-	session  *gemini.ChatSession
-	renderer *glamour.TermRenderer
+	session     *gemini.ChatSession
+	renderer    *glamour.TermRenderer
+	attachments *Attachments
+	sources     *Sources
+	opts        *QueryOptions
 }
 
 var _ MessageHandler = (*GeminiQuery)(nil)
 
 // NewGeminiQuery returns a new GeminiQuery message handler.
-func NewGeminiQuery(io *IO, session *gemini.ChatSession, opts RendererOptions) (*GeminiQuery, error) {
-	renderer, err := opts.newTermRenderer()
+// The handler confirms the function calls requested by the model in the session.
+func NewGeminiQuery(io *IO, session *gemini.ChatSession, attachments *Attachments,
+	sources *Sources, opts *QueryOptions, rendererOptions RendererOptions) (*GeminiQuery, error) {
+	renderer, err := newResponseRenderer(*opts, rendererOptions)
 	if err != nil {
-		return nil, fmt.Errorf("failed to instantiate terminal renderer: %w", err)
+		return nil, err
+	}
+
+	query := &GeminiQuery{
+		IO:          io,
+		session:     session,
+		renderer:    renderer,
+		attachments: attachments,
+		sources:     sources,
+		opts:        opts,
 	}
+	session.SetFunctionCallConfirmation(query.confirmFunctionCall)
+	session.SetRetryNotification(query.notifyRetry)
 
-	return &GeminiQuery{
-		IO:       io,
-		session:  session,
-		renderer: renderer,
-	}, nil
+	return query, nil
+}
+
+// notifyRetry displays the countdown until the failed request is retried.
+func (h *GeminiQuery) notifyRetry(retry int, delay time.Duration, _ error) {
+	h.terminal.Spinner.Start()
+	h.terminal.Spinner.Countdown(retryMessage(retry, h.session.RetryPolicy().MaxRetries),
+		time.Now().Add(delay))
 }
 
 // Handle processes the chat message.
+// The files attached using the system command or the inline syntax are sent
+// along with the message. The attachments are cleared once the request succeeds.
+// The sources used to ground the response are listed after the answer.
+// The user is warned before sending if the chat context approaches the model
+// input token limit. If the response is blocked or truncated, the reason is
+// explained, and the user is offered to retry or to continue the answer.
 func (h *GeminiQuery) Handle(message string) (Response, bool) {
+	inlineAttachments, err := parseInlineAttachments(message)
+	if err != nil {
+		return newErrorResponse(err), false
+	}
+	attachments := append(h.attachments.Files(), inlineAttachments...)
+	if err := gemini.CheckAttachmentSize(attachments); err != nil {
+		return newErrorResponse(err), false
+	}
+
+	if response, ok := h.checkContextSize(message, attachments); !ok {
+		return response, false
+	}
+
+	if h.opts.JSON {
+		return h.handleJSON(message, attachments), false
+	}
+	if h.opts.Stream {
+		return h.handleStream(message, attachments), false
+	}
+
+	ctx, cancel := newRequestContext()
+	defer cancel()
+
 	h.terminal.Spinner.Start()
 	defer h.terminal.Spinner.Stop()
 
-	response, err := h.session.SendMessage(message)
+	start := time.Now()
+	response, err := h.session.SendMessage(ctx, message, attachments...)
 	if err != nil {
-		return newErrorResponse(err), false
+		if issue := promptIssue(err, h.session.SafetySettings()); issue != nil {
+			h.terminal.Spinner.Stop()
+			return h.resolveIssue(message, issue), false
+		}
+		return requestErrorResponse(err), false
 	}
 
-	var b strings.Builder
-	for _, candidate := range response.Candidates {
-		for _, part := range candidate.Content.Parts {
-			_, _ = fmt.Fprint(&b, part.Text)
-		}
+	issue := candidateIssue(firstCandidate(response), h.session.SafetySettings())
+	if issue == nil || !issue.blocked {
+		h.attachments.Clear()
 	}
 
-	rendered, err := h.renderer.Render(b.String())
+	text, sources := responseMarkdown(response, h.session.TurnGroundingMetadata(), h.opts.Citations)
+	h.sources.Set(sources)
+
+	rendered, err := renderResponse(h.renderer, text)
 	if err != nil {
 		return newErrorResponse(fmt.Errorf("failed to format response: %w", err)), false
 	}
 
-	return dataResponse(rendered), false
+	thoughts := formatThoughts(responseThoughts(response), h.opts.Thoughts)
+	footer := h.usageFooter(time.Since(start))
+	if issue == nil {
+		return dataResponse(thoughts + rendered + footer), false
+	}
+
+	h.terminal.Spinner.Stop()
+	if output := thoughts + rendered + footer; strings.TrimSpace(output) != "" {
+		h.terminal.Write(dataResponse(output).String())
+	}
+	return h.resolveIssue(message, issue), false
+}
+
+// handleStream processes the chat message using a streaming request,
+// rendering the response incrementally. The thoughts are written once the
+// model starts generating the answer.
+func (h *GeminiQuery) handleStream(message string, attachments []*gemini.Attachment) Response {
+	ctx, cancel := newRequestContext()
+	defer cancel()
+
+	h.terminal.Spinner.Start()
+	defer h.terminal.Spinner.Stop()
+
+	stream := newStreamRenderer(h.renderer, func(rendered string) {
+		h.terminal.Spinner.Stop()
+		h.terminal.Write(rendered)
+	})
+
+	var (
+		thoughts  strings.Builder
+		sources   sourceList
+		candidate *genai.Candidate
+	)
+	writeThoughts := sync.OnceFunc(func() {
+		if formatted := formatThoughts(thoughts.String(), h.opts.Thoughts); formatted != "" {
+			h.terminal.Spinner.Stop()
+			h.terminal.Write(formatted)
+		}
+	})
+	defer writeThoughts()
+
+	start := time.Now()
+	for response, err := range h.session.SendMessageStream(ctx, message, attachments...) {
+		if err != nil {
+			_ = stream.Flush() // show the partial response received so far
+			if issue := promptIssue(err, h.session.SafetySettings()); issue != nil {
+				h.terminal.Spinner.Stop()
+				return h.resolveIssue(message, issue)
+			}
+			return requestErrorResponse(err)
+		}
+
+		if c := firstCandidate(response); c != nil && c.FinishReason != "" {
+			candidate = c
+		}
+		sources.addResponse(response)
+		thoughts.WriteString(responseThoughts(response))
+		text := responseText(response)
+		if text == "" {
+			continue
+		}
+
+		writeThoughts()
+		if err := stream.Write(text); err != nil {
+			return newErrorResponse(fmt.Errorf("failed to format response: %w", err))
+		}
+	}
+
+	issue := candidateIssue(candidate, h.session.SafetySettings())
+	if issue == nil || !issue.blocked {
+		h.attachments.Clear()
+	}
+	h.sources.Set(sources.list)
+
+	if err := stream.Write(formatSources(sources.list)); err != nil {
+		return newErrorResponse(fmt.Errorf("failed to format response: %w", err))
+	}
+	if err := stream.Flush(); err != nil {
+		return newErrorResponse(fmt.Errorf("failed to format response: %w", err))
+	}
+
+	footer := h.usageFooter(time.Since(start))
+	if issue == nil {
+		return dataResponse(footer)
+	}
+
+	h.terminal.Spinner.Stop()
+	h.terminal.Write(dataResponse(footer).String())
+	return h.resolveIssue(message, issue)
+}
+
+// handleJSON processes the chat message in the JSON output mode, writing
+// the raw JSON response. The response is shown even if it fails validation.
+func (h *GeminiQuery) handleJSON(message string, attachments []*gemini.Attachment) Response {
+	ctx, cancel := newRequestContext()
+	defer cancel()
+
+	h.terminal.Spinner.Start()
+	defer h.terminal.Spinner.Stop()
+
+	start := time.Now()
+	response, err := h.session.SendMessage(ctx, message, attachments...)
+	if err != nil {
+		return requestErrorResponse(err)
+	}
+	h.attachments.Clear()
+
+	text, err := jsonResponse(response, h.opts.Schema)
+	if err != nil {
+		h.terminal.Spinner.Stop()
+		h.terminal.Write(text + "\n")
+		return newErrorResponse(err)
+	}
+
+	return dataResponse(text + h.usageFooter(time.Since(start)))
+}
+
+// usageFooter returns the per-turn usage footer if enabled; otherwise,
+// it returns an empty string.
+func (h *GeminiQuery) usageFooter(latency time.Duration) string {
+	usage := h.session.TurnUsage()
+	if !h.opts.Usage || usage == nil {
+		return ""
+	}
+
+	pricing, _ := gemini.ModelPricing(h.opts.Pricing, h.session.Model())
+	return formatUsageFooter(usage, latency, pricing)
+}
+
+// newResponseRenderer returns a terminal renderer for the model responses,
+// or nil if the raw or JSON output is requested.
+func newResponseRenderer(opts QueryOptions,
+	rendererOptions RendererOptions) (*glamour.TermRenderer, error) {
+	if opts.Raw || opts.JSON {
+		return nil, nil
+	}
+
+	renderer, err := rendererOptions.newTermRenderer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate terminal renderer: %w", err)
+	}
+
+	return renderer, nil
+}
+
+// renderResponse renders the response text using the renderer.
+// If the renderer is nil, the text is returned as is.
+func renderResponse(renderer *glamour.TermRenderer, text string) (string, error) {
+	if renderer == nil {
+		return text, nil
+	}
+	return renderer.Render(text)
+}
+
+// newRequestContext returns a context for a single model request, which is
+// canceled when the user interrupts the request with Ctrl-C.
+func newRequestContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+// requestErrorResponse returns a response for the failed model request.
+// The interrupted request is reported as canceled rather than failed.
+func requestErrorResponse(err error) Response {
+	if errors.Is(err, context.Canceled) {
+		return dataResponse(canceledMessage)
+	}
+	return newErrorResponse(err)
+}
+
+// retryMessage returns the message displayed while waiting to retry
+// a failed request.
+func retryMessage(retry, maxRetries int) string {
+	return fmt.Sprintf("Retrying (%d/%d) in", retry, maxRetries)
+}
+
+// firstCandidate returns the first candidate of the response, if any.
+func firstCandidate(response *genai.GenerateContentResponse) *genai.Candidate {
+	if len(response.Candidates) == 0 {
+		return nil
+	}
+	return response.Candidates[0]
+}
+
+// responseText returns the concatenated text of the response parts, excluding
+// the thoughts. The executed code and its results are formatted as labelled
+// sections. Multiple response candidates are separated by a horizontal rule.
+func responseText(response *genai.GenerateContentResponse) string {
+	var b strings.Builder
+	for i, candidate := range response.Candidates {
+		if candidate.Content == nil {
+			continue
+		}
+		if i > 0 {
+			b.WriteString("\n\n---\n\n")
+		}
+		for _, part := range candidate.Content.Parts {
+			switch {
+			case part.Thought:
+				// the thoughts are displayed separately
+			case part.ExecutableCode != nil:
+				b.WriteString(formatExecutableCode(part.ExecutableCode))
+			case part.CodeExecutionResult != nil:
+				b.WriteString(formatCodeExecutionResult(part.CodeExecutionResult))
+			default:
+				b.WriteString(part.Text)
+			}
+		}
+	}
+	return b.String()
 }
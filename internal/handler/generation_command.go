@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/manifoldco/promptui"
+	"github.com/reugn/gemini-cli/gemini"
+)
+
+const modelDefault = "model default"
+
+// GenerationCommand processes the generation parameters system command.
+// It implements the MessageHandler interface.
+type GenerationCommand struct {
+	*IO
+	session *gemini.ChatSession
+}
+
+var _ MessageHandler = (*GenerationCommand)(nil)
+
+// NewGenerationCommand returns a new GenerationCommand.
+func NewGenerationCommand(io *IO, session *gemini.ChatSession) *GenerationCommand {
+	return &GenerationCommand{
+		IO:      io,
+		session: session,
+	}
+}
+
+// Handle processes the generation parameters system command.
+func (h *GenerationCommand) Handle(_ string) (Response, bool) {
+	defer h.terminal.Write(h.terminalPrompt)
+	generationConfig := h.session.GenerationConfig()
+	parameter, err := h.selectParameter(generationConfig)
+	if err != nil {
+		return newErrorResponse(err), false
+	}
+
+	value, err := h.promptParameterValue(generationConfig, parameter)
+	if err != nil {
+		return newErrorResponse(err), false
+	}
+
+	if value == generationConfig.Get(parameter) {
+		return dataResponse(unchangedMessage), false
+	}
+
+	if err := generationConfig.Set(parameter, value); err != nil {
+		return newErrorResponse(err), false
+	}
+
+	if err := h.session.SetGenerationConfig(generationConfig); err != nil {
+		return newErrorResponse(err), false
+	}
+
+	if value == "" {
+		return dataResponse(fmt.Sprintf("Reset %q to the %s.", parameter, modelDefault)), false
+	}
+	return dataResponse(fmt.Sprintf("Set %q to %s.", parameter, value)), false
+}
+
+// selectParameter returns the selected generation parameter name.
+func (h *GenerationCommand) selectParameter(generationConfig *gemini.GenerationConfig) (string, error) {
+	items := make([]string, len(gemini.GenerationParameters))
+	for i, parameter := range gemini.GenerationParameters {
+		value := generationConfig.Get(parameter)
+		if value == "" {
+			value = modelDefault
+		}
+		items[i] = fmt.Sprintf("%s: %s", parameter, value)
+	}
+
+	prompt := promptui.Select{
+		Label:        "Select generation parameter",
+		HideSelected: true,
+		Items:        items,
+	}
+
+	i, _, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+
+	return gemini.GenerationParameters[i], nil
+}
+
+// promptParameterValue returns the validated value for the generation parameter.
+func (h *GenerationCommand) promptParameterValue(generationConfig *gemini.GenerationConfig,
+	parameter string) (string, error) {
+	prompt := promptui.Prompt{
+		Label:       fmt.Sprintf("Enter %s value (empty for the %s)", parameter, modelDefault),
+		Default:     generationConfig.Get(parameter),
+		AllowEdit:   true,
+		HideEntered: true,
+		Validate: func(input string) error {
+			return generationConfig.Merge(nil).Set(parameter, input)
+		},
+	}
+
+	return prompt.Run()
+}
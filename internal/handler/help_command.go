@@ -36,9 +36,24 @@ func (h *HelpCommand) Handle(_ string) (Response, bool) {
 	b.WriteString("Use a command prefixed with an exclamation mark (e.g., `!h`).\n")
 	fmt.Fprintf(&b, "* `%s` - Select the generative model system prompt.\n", cli.SystemCmdSelectPrompt)
 	fmt.Fprintf(&b, "* `%s` - Select from a list of generative model operations.\n", cli.SystemCmdModel)
+	fmt.Fprintf(&b, "* `%s` - Adjust the generation parameters.\n", cli.SystemCmdGeneration)
+	fmt.Fprintf(&b, "* `%s` - Select the model thoughts display mode.\n", cli.SystemCmdThoughts)
+	fmt.Fprintf(&b, "* `%s` - Change the safety thresholds and enable or disable tools.\n",
+		cli.SystemCmdSettings)
 	fmt.Fprintf(&b, "* `%s` - Select from a list of chat history operations.\n", cli.SystemCmdHistory)
 	fmt.Fprintf(&b, "* `%s` - Toggle the input mode.\n", cli.SystemCmdSelectInputMode)
+	fmt.Fprintf(&b, "* `%s [path...]` - Attach files to the next message, or select from a list of "+
+		"attachment operations.\n", cli.SystemCmdAttach)
+	fmt.Fprintf(&b, "* `%s` - Show the sources of the last response.\n", cli.SystemCmdSources)
+	fmt.Fprintf(&b, "* `%s` - List the function tools and the MCP servers.\n", cli.SystemCmdTools)
+	fmt.Fprintf(&b, "* `%s` - Show the chat context size in tokens.\n", cli.SystemCmdTokens)
+	fmt.Fprintf(&b, "* `%s` - Summarize the earlier chat turns to reduce the context size.\n",
+		cli.SystemCmdCompact)
+	fmt.Fprintf(&b, "* `%s [on|off]` - Show the session token usage, or toggle the per-turn usage footer.\n",
+		cli.SystemCmdUsage)
 	fmt.Fprintf(&b, "* `%s` - Exit the application.\n", cli.SystemCmdQuit)
+	fmt.Fprintf(&b, "\nFiles can also be attached inline using the `%spath/to/file` syntax.\n",
+		attachmentPrefix)
 
 	rendered, err := h.renderer.Render(b.String())
 	if err != nil {
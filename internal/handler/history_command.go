@@ -1,40 +1,69 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
 	"slices"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/manifoldco/promptui"
 	"github.com/reugn/gemini-cli/gemini"
 	"github.com/reugn/gemini-cli/internal/config"
+	"github.com/reugn/gemini-cli/internal/history"
 	"google.golang.org/genai"
 )
 
-var historyOptions = []string{
-	"Clear chat history",
-	"Store chat history",
-	"Load chat history",
-	"Delete stored history records",
-}
+const (
+	// emptyStoreMessage is the response when there are no stored records.
+	emptyStoreMessage = "There are no stored history records."
+	// entriesPageSize is the number of the stored records listed at once.
+	entriesPageSize = 10
+)
+
+var (
+	historyOptions = []string{
+		"Clear chat history",
+		"Store chat history",
+		"Save to the loaded record",
+		"Load chat history",
+		"Search stored records",
+		"Rename a stored record",
+		"Duplicate a stored record",
+		"Delete a stored record",
+		"Delete all stored records",
+	}
+	restoreOptions = []string{
+		"Restore the conversation settings",
+		"Keep the current settings",
+	}
+)
 
 // HistoryCommand processes the chat history system commands.
 // It implements the MessageHandler interface.
 type HistoryCommand struct {
 	*IO
-	session       *gemini.ChatSession
-	configuration *config.Configuration
+	session         *gemini.ChatSession
+	applicationData *config.ApplicationData
+	store           *history.Store
+	// loaded is the ID of the record the chat history was last loaded from
+	// or stored to, which can be saved back.
+	loaded string
 }
 
 var _ MessageHandler = (*HistoryCommand)(nil)
 
 // NewHistoryCommand returns a new HistoryCommand.
-func NewHistoryCommand(io *IO, session *gemini.ChatSession,
-	configuration *config.Configuration) *HistoryCommand {
+func NewHistoryCommand(io *IO, session *gemini.ChatSession, applicationData *config.ApplicationData,
+	store *history.Store) *HistoryCommand {
 	return &HistoryCommand{
-		IO:            io,
-		session:       session,
-		configuration: configuration,
+		IO:              io,
+		session:         session,
+		applicationData: applicationData,
+		store:           store,
 	}
 }
 
@@ -51,112 +80,430 @@ func (h *HistoryCommand) Handle(_ string) (Response, bool) {
 	case historyOptions[1]:
 		response = h.handleStore()
 	case historyOptions[2]:
-		response = h.handleLoad()
+		response = h.handleOverwrite()
 	case historyOptions[3]:
+		response = h.handleLoad()
+	case historyOptions[4]:
+		response = h.handleSearch()
+	case historyOptions[5]:
+		response = h.handleRename()
+	case historyOptions[6]:
+		response = h.handleDuplicate()
+	case historyOptions[7]:
 		response = h.handleDelete()
+	case historyOptions[8]:
+		response = h.handleDeleteAll()
 	default:
 		response = newErrorResponse(fmt.Errorf("unsupported option: %s", option))
 	}
 	return response, false
 }
 
-// handleClear handles the chat history clear request.
+// handleClear handles the chat history clear request. The cleared history
+// is no longer associated with the loaded record.
 func (h *HistoryCommand) handleClear() Response {
 	h.terminal.Write(h.terminalPrompt)
 	if err := h.session.ClearHistory(); err != nil {
 		return newErrorResponse(err)
 	}
+	h.loaded = ""
 
 	return dataResponse("Cleared the chat history.")
 }
 
-// handleStore handles the chat history store request.
+// handleStore handles the chat history store request. The conversation is
+// stored along with the model, the system prompt, the generation parameters
+// and the context size.
 func (h *HistoryCommand) handleStore() Response {
 	defer h.terminal.Write(h.terminalPrompt)
-	historyLabel, err := h.promptHistoryLabel()
+	label, err := h.promptHistoryLabel("")
 	if err != nil {
 		return newErrorResponse(err)
 	}
 
-	timeLabel := time.Now().In(time.Local).Format(time.DateTime)
-	recordLabel := fmt.Sprintf("%s - %s", timeLabel, historyLabel)
+	conversation := history.NewConversation(label, h.currentSession())
+	conversation.Tokens = h.countTokens()
+	if err := h.store.Save(conversation); err != nil {
+		return newErrorResponse(err)
+	}
+	h.loaded = conversation.ID
+
+	return dataResponse(fmt.Sprintf("%q has been saved to the history store.", label))
+}
 
-	h.configuration.Data.AddHistoryRecord(
-		recordLabel,
-		h.session.GetHistory(),
-	)
+// handleOverwrite handles the request to save the chat history back to the
+// record it was loaded from or stored to, preserving the record label.
+func (h *HistoryCommand) handleOverwrite() Response {
+	h.terminal.Write(h.terminalPrompt)
+	conversation, err := h.store.Load(h.loaded)
+	if err != nil {
+		if errors.Is(err, history.ErrNotFound) {
+			h.loaded = ""
+		}
+		return newErrorResponse(err)
+	}
 
-	if err := h.configuration.Flush(); err != nil {
+	conversation.SetSession(h.currentSession())
+	conversation.Tokens = h.countTokens()
+	if err := h.store.Save(conversation); err != nil {
 		return newErrorResponse(err)
 	}
 
-	return dataResponse(fmt.Sprintf("%q has been saved to the file.", recordLabel))
+	return dataResponse(fmt.Sprintf("%q has been updated in the history store.", conversation.Label))
 }
 
 // handleLoad handles the chat history load request.
 func (h *HistoryCommand) handleLoad() Response {
 	defer h.terminal.Write(h.terminalPrompt)
-	label, history, err := h.loadHistory()
+	entry, err := h.selectEntry("Select conversation history to load")
 	if err != nil {
 		return newErrorResponse(err)
 	}
+	if entry == nil {
+		return dataResponse(emptyStoreMessage)
+	}
 
-	if err := h.session.SetHistory(history); err != nil {
+	return h.loadConversation(entry)
+}
+
+// loadConversation loads the stored conversation to the chat history. If the
+// conversation was recorded with different settings, the user is offered to
+// restore them.
+func (h *HistoryCommand) loadConversation(entry *history.Entry) Response {
+	conversation, err := h.store.Load(entry.ID)
+	if err != nil {
 		return newErrorResponse(err)
 	}
 
-	return dataResponse(fmt.Sprintf("%q has been loaded to the chat history.", label))
+	session := conversation.Session()
+	restore, err := h.confirmRestore(session)
+	if err != nil {
+		return newErrorResponse(err)
+	}
+	if !restore {
+		if err := h.session.SetHistory(session.History); err != nil {
+			return newErrorResponse(err)
+		}
+		h.loaded = entry.ID
+		return dataResponse(fmt.Sprintf("%q has been loaded to the chat history.", entry.Label))
+	}
+
+	if err := session.Restore(h.session); err != nil {
+		return newErrorResponse(err)
+	}
+	h.loaded = entry.ID
+	return dataResponse(fmt.Sprintf("%q has been loaded along with its settings.", entry.Label))
 }
 
-// handleDelete handles deletion of the stored history records.
+// handleRename handles the request to change the label of a stored record.
+func (h *HistoryCommand) handleRename() Response {
+	defer h.terminal.Write(h.terminalPrompt)
+	entry, err := h.selectEntry("Select history record to rename")
+	if err != nil {
+		return newErrorResponse(err)
+	}
+	if entry == nil {
+		return dataResponse(emptyStoreMessage)
+	}
+
+	label, err := h.promptHistoryLabel(entry.Label)
+	if err != nil {
+		return newErrorResponse(err)
+	}
+	if label == entry.Label {
+		return dataResponse(unchangedMessage)
+	}
+	if err := h.store.Rename(entry.ID, label); err != nil {
+		return newErrorResponse(err)
+	}
+
+	return dataResponse(fmt.Sprintf("%q has been renamed to %q.", entry.Label, label))
+}
+
+// handleDuplicate handles the request to store a copy of a stored record.
+func (h *HistoryCommand) handleDuplicate() Response {
+	defer h.terminal.Write(h.terminalPrompt)
+	entry, err := h.selectEntry("Select history record to duplicate")
+	if err != nil {
+		return newErrorResponse(err)
+	}
+	if entry == nil {
+		return dataResponse(emptyStoreMessage)
+	}
+
+	label, err := h.promptHistoryLabel(entry.Label + " (copy)")
+	if err != nil {
+		return newErrorResponse(err)
+	}
+	if _, err := h.store.Duplicate(entry.ID, label); err != nil {
+		return newErrorResponse(err)
+	}
+
+	return dataResponse(fmt.Sprintf("%q has been duplicated as %q.", entry.Label, label))
+}
+
+// handleDelete handles deletion of a stored history record.
 func (h *HistoryCommand) handleDelete() Response {
-	h.terminal.Write(h.terminalPrompt)
-	h.configuration.Data.History = make(map[string][]*gemini.SerializableContent)
-	if err := h.configuration.Flush(); err != nil {
+	defer h.terminal.Write(h.terminalPrompt)
+	entry, err := h.selectEntry("Select history record to delete")
+	if err != nil {
 		return newErrorResponse(err)
 	}
-	return dataResponse("History records have been removed from the file.")
+	if entry == nil {
+		return dataResponse(emptyStoreMessage)
+	}
+
+	confirmed, err := confirmAction(fmt.Sprintf("Delete %q", entry.Label))
+	if err != nil {
+		return newErrorResponse(err)
+	}
+	if !confirmed {
+		return dataResponse("The history store is unchanged.")
+	}
+
+	if err := h.store.Delete(entry.ID); err != nil {
+		return newErrorResponse(err)
+	}
+	if h.loaded == entry.ID {
+		h.loaded = ""
+	}
+
+	return dataResponse(fmt.Sprintf("%q has been removed from the history store.", entry.Label))
+}
+
+// currentSession returns the current state of the chat session.
+func (h *HistoryCommand) currentSession() *history.Session {
+	return history.NewSession(h.session, h.applicationData.SystemPromptLabel(h.session.SystemInstruction()))
 }
 
-// loadHistory returns history data to be set.
-func (h *HistoryCommand) loadHistory() (string, []*genai.Content, error) {
-	promptNames := make([]string, len(h.configuration.Data.History)+1)
-	promptNames[0] = empty
-	i := 1
-	for p := range h.configuration.Data.History {
-		promptNames[i] = p
-		i++
+// confirmRestore lists the settings of the conversation that differ from the
+// current ones, and returns true if the user chooses to restore them.
+func (h *HistoryCommand) confirmRestore(session *history.Session) (bool, error) {
+	changes := h.settingChanges(session)
+	if len(changes) == 0 {
+		return false, nil
 	}
+
+	h.terminal.Write("The conversation was recorded with different settings:\n" +
+		strings.Join(changes, "\n") + "\n")
 	prompt := promptui.Select{
-		Label:        "Select conversation history to load",
+		Label:        "Select an action",
 		HideSelected: true,
-		Items:        promptNames,
-		CursorPos:    slices.Index(promptNames, empty),
+		Items:        restoreOptions,
 	}
 
-	_, result, err := prompt.Run()
+	i, _, err := prompt.Run()
+	if err != nil {
+		return false, err
+	}
+	return i == 0, nil
+}
+
+// settingChanges returns the descriptions of the recorded session settings
+// that differ from the current ones. The settings of the records stored by
+// the earlier versions of the application are unknown.
+func (h *HistoryCommand) settingChanges(session *history.Session) []string {
+	if session.Model == "" {
+		return nil
+	}
+
+	var changes []string
+	if model := h.session.Model(); session.Model != model {
+		changes = append(changes, fmt.Sprintf("  model: %s (current: %s)", session.Model, model))
+	}
+	if systemInstruction := h.session.SystemInstruction(); contentText(session.SystemInstruction) !=
+		contentText(systemInstruction) {
+		changes = append(changes, fmt.Sprintf("  system prompt: %s (current: %s)",
+			systemPromptName(session.SystemPrompt, session.SystemInstruction),
+			systemPromptName(h.applicationData.SystemPromptLabel(systemInstruction), systemInstruction)))
+	}
+	if generationConfig := h.session.GenerationConfig(); session.GenerationConfig != nil &&
+		!reflect.DeepEqual(session.GenerationConfig, generationConfig) {
+		changes = append(changes, fmt.Sprintf("  generation parameters: %s (current: %s)",
+			formatGenerationConfig(session.GenerationConfig), formatGenerationConfig(generationConfig)))
+	}
+
+	return changes
+}
+
+// countTokens returns the size of the chat context in tokens, or zero if it
+// cannot be counted.
+func (h *HistoryCommand) countTokens() int32 {
+	ctx, cancel := newRequestContext()
+	defer cancel()
+
+	h.terminal.Spinner.Start()
+	defer h.terminal.Spinner.Stop()
+	size, err := h.session.CountTokens(ctx, "")
+	if err != nil {
+		return 0 // the token count is informational
+	}
+	return size.Tokens
+}
+
+// handleDeleteAll handles deletion of all stored history records.
+func (h *HistoryCommand) handleDeleteAll() Response {
+	defer h.terminal.Write(h.terminalPrompt)
+	confirmed, err := confirmAction("Delete all stored history records")
+	if err != nil {
+		return newErrorResponse(err)
+	}
+	if !confirmed {
+		return dataResponse("The history store is unchanged.")
+	}
+
+	if err := h.store.Clear(); err != nil {
+		return newErrorResponse(err)
+	}
+	h.loaded = ""
+	return dataResponse("History records have been removed from the history store.")
+}
+
+// confirmAction returns true if the user confirms the action.
+func confirmAction(label string) (bool, error) {
+	prompt := promptui.Prompt{
+		Label:     label,
+		IsConfirm: true,
+	}
+	if _, err := prompt.Run(); err != nil {
+		if errors.Is(err, promptui.ErrAbort) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// selectEntry returns the index entry of the selected stored conversation,
+// or nil if there are none. The conversations are listed by the update time,
+// and can be searched by fuzzy matching.
+func (h *HistoryCommand) selectEntry(label string) (*history.Entry, error) {
+	entries, err := h.store.List()
 	if err != nil {
-		return result, nil, err
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	items := newEntryItems(entries)
+	prompt := promptui.Select{
+		Label:        label,
+		HideSelected: true,
+		Items:        items,
+		Size:         min(len(items), entriesPageSize),
+		Searcher: func(input string, index int) bool {
+			return fuzzyMatch(items[index].text, input)
+		},
+	}
+
+	i, _, err := prompt.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	return entries[i], nil
+}
+
+// entryItem is the select prompt item of a stored conversation. The selected
+// item is resolved by comparing the items, so the entries are listed by pointer
+// to distinguish the ones with the same string representation.
+type entryItem struct {
+	text string
+}
+
+// String returns the string representation of the index entry.
+func (i *entryItem) String() string {
+	return i.text
+}
+
+// newEntryItems returns the select prompt items of the index entries.
+func newEntryItems(entries []*history.Entry) []*entryItem {
+	items := make([]*entryItem, len(entries))
+	for i, entry := range entries {
+		items[i] = &entryItem{text: formatEntry(entry)}
 	}
+	return items
+}
 
-	if result == empty {
-		return result, nil, nil
+// formatEntry returns the string representation of the stored conversation
+// index entry, starting with the update time.
+func formatEntry(entry *history.Entry) string {
+	details := []string{fmt.Sprintf("%d turns", entry.Turns)}
+	if entry.Tokens > 0 {
+		details = append(details, fmt.Sprintf("%d tokens", entry.Tokens))
 	}
+	if entry.Model != "" {
+		details = append([]string{entry.Model}, details...)
+	}
+	return fmt.Sprintf("%s - %s (%s)", entry.Updated.Local().Format(time.DateTime),
+		entry.Label, strings.Join(details, ", "))
+}
 
-	serializedContent := h.configuration.Data.History[result]
-	content := make([]*genai.Content, len(serializedContent))
-	for i, c := range serializedContent {
-		content[i] = c.ToContent()
+// fuzzyMatch returns true if the characters of the pattern appear in the text
+// in the same order, ignoring case and the whitespace of the pattern.
+func fuzzyMatch(text, pattern string) bool {
+	text = strings.ToLower(text)
+	for _, r := range strings.ToLower(pattern) {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		i := strings.IndexRune(text, r)
+		if i < 0 {
+			return false
+		}
+		text = text[i+utf8.RuneLen(r):]
 	}
+	return true
+}
 
-	return result, content, nil
+// systemPromptName returns the name of the system prompt shown to the user.
+func systemPromptName(label string, systemInstruction *genai.Content) string {
+	switch {
+	case label != "":
+		return fmt.Sprintf("%q", label)
+	case systemInstruction != nil:
+		return "custom"
+	default:
+		return "none"
+	}
 }
 
-// promptHistoryLabel returns a label for the history record.
-func (h *HistoryCommand) promptHistoryLabel() (string, error) {
+// formatGenerationConfig returns the string representation of the generation
+// parameters that are set.
+func formatGenerationConfig(generationConfig *gemini.GenerationConfig) string {
+	var parameters []string
+	for _, parameter := range gemini.GenerationParameters {
+		if value := generationConfig.Get(parameter); value != "" {
+			parameters = append(parameters, parameter+"="+value)
+		}
+	}
+	if len(parameters) == 0 {
+		return modelDefault + "s"
+	}
+	return strings.Join(parameters, ", ")
+}
+
+// contentText returns the concatenated text parts of the content.
+func contentText(content *genai.Content) string {
+	if content == nil {
+		return ""
+	}
+	var text strings.Builder
+	for _, part := range content.Parts {
+		text.WriteString(part.Text)
+	}
+	return text.String()
+}
+
+// promptHistoryLabel returns a label for the history record, prefilled with
+// the default label.
+func (h *HistoryCommand) promptHistoryLabel(defaultLabel string) (string, error) {
 	prompt := promptui.Prompt{
 		Label:       "Enter a label for the history record",
 		HideEntered: true,
+		Default:     defaultLabel,
+		AllowEdit:   true,
 	}
 
 	label, err := prompt.Run()
@@ -167,12 +514,20 @@ func (h *HistoryCommand) promptHistoryLabel() (string, error) {
 	return label, nil
 }
 
-// selectHistoryOption returns the selected history action name.
+// selectHistoryOption returns the selected history action name. Saving to
+// the loaded record is offered only if there is one.
 func (h *HistoryCommand) selectHistoryOption() (string, error) {
+	options := historyOptions
+	if h.loaded == "" {
+		options = slices.DeleteFunc(slices.Clone(options), func(option string) bool {
+			return option == historyOptions[2]
+		})
+	}
 	prompt := promptui.Select{
 		Label:        "Select history option",
 		HideSelected: true,
-		Items:        historyOptions,
+		Items:        options,
+		Size:         len(options),
 	}
 
 	_, result, err := prompt.Run()
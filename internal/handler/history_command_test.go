@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/manifoldco/promptui/list"
+	"github.com/reugn/gemini-cli/internal/history"
+)
+
+func TestEntryItemsSelection(t *testing.T) {
+	updated := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	// the duplicated conversations have the same string representation
+	entries := []*history.Entry{
+		{ID: "a", Label: "notes", Updated: updated, Turns: 2},
+		{ID: "b", Label: "notes", Updated: updated, Turns: 2},
+		{ID: "c", Label: "other", Updated: updated, Turns: 1},
+	}
+	items := newEntryItems(entries)
+	if items[0].String() != items[1].String() {
+		t.Fatalf("expected the same representation: %q, %q", items[0], items[1])
+	}
+
+	selector, err := list.New(items, len(items))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, entry := range entries {
+		if selected := selector.Index(); entries[selected] != entry {
+			t.Errorf("cursor %d selects entry %s, want %s", i, entries[selected].ID, entry.ID)
+		}
+		selector.Next()
+	}
+
+	// the selection resolves to the entry when the list is filtered
+	selector.Searcher = func(input string, index int) bool {
+		return fuzzyMatch(items[index].text, input)
+	}
+	selector.Search("notes")
+	selector.Next()
+	if selected := selector.Index(); entries[selected].ID != "b" {
+		t.Errorf("filtered selection = %s, want b", entries[selected].ID)
+	}
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		text, pattern string
+		match         bool
+	}{
+		{"2025-01-01 12:00:00 - Notes (2 turns)", "notes", true},
+		{"2025-01-01 12:00:00 - Notes (2 turns)", "2025 nts", true},
+		{"2025-01-01 12:00:00 - Notes (2 turns)", "stoN", false},
+		{"Ünïcode label", "ünï", true},
+		{"any", "", true},
+	}
+	for _, tt := range tests {
+		if match := fuzzyMatch(tt.text, tt.pattern); match != tt.match {
+			t.Errorf("fuzzyMatch(%q, %q) = %t, want %t", tt.text, tt.pattern, match, tt.match)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	"github.com/reugn/gemini-cli/internal/history"
+	"github.com/reugn/gemini-cli/internal/terminal/color"
+)
+
+// FormatMatches returns the string representation of the chat history search
+// matches, numbered by rank, along with their snippets. The matched terms of
+// the snippets are wrapped using the highlight function.
+func FormatMatches(matches []*history.Match, highlight func(string) string) string {
+	var builder strings.Builder
+	for i, match := range matches {
+		fmt.Fprintf(&builder, "%d. %s\n", i+1, formatEntry(match.Entry))
+		for _, snippet := range match.Snippets {
+			fmt.Fprintf(&builder, "   %s: %s\n", snippet.Role, snippet.Highlight(highlight))
+		}
+	}
+	return builder.String()
+}
+
+// SelectMatch returns the selected chat history search match.
+func SelectMatch(matches []*history.Match, label string) (*history.Match, error) {
+	items := make([]string, len(matches))
+	for i, match := range matches {
+		items[i] = fmt.Sprintf("%d. %s", i+1, formatEntry(match.Entry))
+	}
+	prompt := promptui.Select{
+		Label:        label,
+		HideSelected: true,
+		Items:        items,
+		Size:         min(len(items), entriesPageSize),
+		Searcher: func(input string, index int) bool {
+			return fuzzyMatch(items[index], input)
+		},
+	}
+
+	i, _, err := prompt.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	return matches[i], nil
+}
+
+// handleSearch handles the full-text search of the stored history records,
+// and loads the selected matching conversation.
+func (h *HistoryCommand) handleSearch() Response {
+	defer h.terminal.Write(h.terminalPrompt)
+	prompt := promptui.Prompt{
+		Label:       "Enter the search terms",
+		HideEntered: true,
+	}
+	query, err := prompt.Run()
+	if err != nil {
+		return newErrorResponse(err)
+	}
+
+	matches, err := h.store.Search(query)
+	if err != nil {
+		return newErrorResponse(err)
+	}
+	if len(matches) == 0 {
+		return dataResponse(fmt.Sprintf("No stored history records match %q.", query))
+	}
+
+	h.terminal.Write(FormatMatches(matches, color.Cyan))
+	match, err := SelectMatch(matches, "Select conversation history to load")
+	if err != nil {
+		return newErrorResponse(err)
+	}
+
+	return h.loadConversation(match.Entry)
+}
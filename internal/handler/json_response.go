@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/reugn/gemini-cli/internal/jsonschema"
+	"google.golang.org/genai"
+)
+
+var errInvalidJSON = errors.New("response is not valid JSON")
+
+// jsonResponse returns the JSON text of the first response candidate.
+// If the schema is not nil, the JSON value is validated against it.
+// The text is returned along with the validation error, if any.
+func jsonResponse(response *genai.GenerateContentResponse, schema map[string]any) (string, error) {
+	var b strings.Builder
+	if len(response.Candidates) > 0 && response.Candidates[0].Content != nil {
+		for _, part := range response.Candidates[0].Content.Parts {
+			if !part.Thought {
+				b.WriteString(part.Text)
+			}
+		}
+	}
+	text := strings.TrimSpace(b.String())
+
+	if !json.Valid([]byte(text)) {
+		return text, errInvalidJSON
+	}
+	if schema != nil {
+		if err := jsonschema.ValidateJSON(schema, []byte(text)); err != nil {
+			return text, fmt.Errorf("response does not match the schema: %w", err)
+		}
+	}
+
+	return text, nil
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/reugn/gemini-cli/internal/jsonschema"
+	"google.golang.org/genai"
+)
+
+func TestJSONResponse(t *testing.T) {
+	schema := map[string]any{
+		"type":     "object",
+		"required": []any{"name"},
+		"properties": map[string]any{
+			"name": map[string]any{"type": "string"},
+		},
+	}
+	tests := []struct {
+		name   string
+		parts  []*genai.Part
+		schema map[string]any
+		text   string
+		// err is the expected error kind: "invalid", "schema" or "" if valid
+		err string
+	}{
+		{"valid", []*genai.Part{{Text: ` {"name": "a"} `}}, schema, `{"name": "a"}`, ""},
+		{"no schema", []*genai.Part{{Text: `[1, 2]`}}, nil, `[1, 2]`, ""},
+		{"split parts", []*genai.Part{{Text: `{"name":`}, {Text: ` "a"}`}}, schema, `{"name": "a"}`, ""},
+		{"thoughts skipped", []*genai.Part{
+			{Text: "Let me think {", Thought: true},
+			{Text: `{"name": "a"}`},
+		}, schema, `{"name": "a"}`, ""},
+		{"invalid json", []*genai.Part{{Text: "Sure! {\"name\": \"a\"}"}}, schema, "Sure! {\"name\": \"a\"}", "invalid"},
+		{"truncated json", []*genai.Part{{Text: `{"name": "a`}}, nil, `{"name": "a`, "invalid"},
+		{"empty", nil, nil, "", "invalid"},
+		{"schema mismatch", []*genai.Part{{Text: `{"name": 1}`}}, schema, `{"name": 1}`, "schema"},
+		{"schema missing property", []*genai.Part{{Text: `{}`}}, schema, `{}`, "schema"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+				Content: genai.NewContentFromParts(tt.parts, genai.RoleModel),
+			}}}
+			text, err := jsonResponse(response, tt.schema)
+			if text != tt.text {
+				t.Errorf("text = %q, want %q", text, tt.text)
+			}
+
+			var validationErr *jsonschema.ValidationError
+			switch tt.err {
+			case "":
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			case "invalid":
+				if !errors.Is(err, errInvalidJSON) {
+					t.Errorf("expected errInvalidJSON, got %v", err)
+				}
+			case "schema":
+				if !errors.As(err, &validationErr) {
+					t.Errorf("expected validation error, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONResponseNoCandidates(t *testing.T) {
+	if _, err := jsonResponse(&genai.GenerateContentResponse{}, nil); !errors.Is(err, errInvalidJSON) {
+		t.Errorf("expected errInvalidJSON, got %v", err)
+	}
+}
@@ -18,18 +18,16 @@ var modelOptions = []string{
 // It implements the MessageHandler interface.
 type ModelCommand struct {
 	*IO
-	session             *gemini.ChatSession
-	generativeModelName string
+	session *gemini.ChatSession
 }
 
 var _ MessageHandler = (*ModelCommand)(nil)
 
 // NewModelCommand returns a new ModelCommand.
-func NewModelCommand(io *IO, session *gemini.ChatSession, modelName string) *ModelCommand {
+func NewModelCommand(io *IO, session *gemini.ChatSession) *ModelCommand {
 	return &ModelCommand{
-		IO:                  io,
-		session:             session,
-		generativeModelName: modelName,
+		IO:      io,
+		session: session,
 	}
 }
 
@@ -61,7 +59,7 @@ func (h *ModelCommand) handleSelectModel() Response {
 		return newErrorResponse(err)
 	}
 
-	if h.generativeModelName == modelName {
+	if h.session.Model() == modelName {
 		return dataResponse(unchangedMessage)
 	}
 
@@ -69,8 +67,6 @@ func (h *ModelCommand) handleSelectModel() Response {
 		return newErrorResponse(err)
 	}
 
-	h.generativeModelName = modelName
-
 	return dataResponse(fmt.Sprintf("Selected %q generative model.", modelName))
 }
 
@@ -110,7 +106,7 @@ func (h *ModelCommand) selectModel(models []string) (string, error) {
 		Label:        modelOptions[0],
 		HideSelected: true,
 		Items:        models,
-		CursorPos:    slices.Index(models, h.generativeModelName),
+		CursorPos:    slices.Index(models, h.session.Model()),
 		Searcher: func(input string, index int) bool {
 			return strings.Contains(models[index], input)
 		},
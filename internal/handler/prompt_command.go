@@ -14,25 +14,30 @@ import (
 // It implements the MessageHandler interface.
 type SystemPromptCommand struct {
 	*IO
-	session         *gemini.ChatSession
-	applicationData *config.ApplicationData
-
-	systemPrompt string
+	session          *gemini.ChatSession
+	applicationData  *config.ApplicationData
+	generationConfig *gemini.GenerationConfig
 }
 
 var _ MessageHandler = (*SystemPromptCommand)(nil)
 
 // NewSystemPromptCommand returns a new SystemPromptCommand.
+// The generation config contains the parameters that take precedence over
+// the system prompt specific ones.
 func NewSystemPromptCommand(io *IO, session *gemini.ChatSession,
-	applicationData *config.ApplicationData) *SystemPromptCommand {
+	applicationData *config.ApplicationData,
+	generationConfig *gemini.GenerationConfig) *SystemPromptCommand {
 	return &SystemPromptCommand{
-		IO:              io,
-		session:         session,
-		applicationData: applicationData,
+		IO:               io,
+		session:          session,
+		applicationData:  applicationData,
+		generationConfig: generationConfig,
 	}
 }
 
 // Handle processes the chat prompt system command.
+// The generation parameters configured for the selected system prompt are
+// applied along with the system instruction.
 func (h *SystemPromptCommand) Handle(_ string) (Response, bool) {
 	defer h.terminal.Write(h.terminalPrompt)
 	label, systemPrompt, err := h.selectSystemPrompt()
@@ -44,6 +49,12 @@ func (h *SystemPromptCommand) Handle(_ string) (Response, bool) {
 		return newErrorResponse(err), false
 	}
 
+	generationConfig := h.applicationData.SystemPromptGenerationConfig(label).
+		Merge(h.generationConfig)
+	if err := h.session.SetGenerationConfig(generationConfig); err != nil {
+		return newErrorResponse(err), false
+	}
+
 	return dataResponse(fmt.Sprintf("Selected %q system instruction.", label)), false
 }
 
@@ -56,11 +67,15 @@ func (h *SystemPromptCommand) selectSystemPrompt() (string, *genai.Content, erro
 		promptNames[i] = p
 		i++
 	}
+	current := h.applicationData.SystemPromptLabel(h.session.SystemInstruction())
+	if current == "" {
+		current = empty
+	}
 	prompt := promptui.Select{
 		Label:        "Select system instruction",
 		HideSelected: true,
 		Items:        promptNames,
-		CursorPos:    slices.Index(promptNames, h.systemPrompt),
+		CursorPos:    max(slices.Index(promptNames, current), 0),
 	}
 
 	_, result, err := prompt.Run()
@@ -68,7 +83,6 @@ func (h *SystemPromptCommand) selectSystemPrompt() (string, *genai.Content, erro
 		return result, nil, err
 	}
 
-	h.systemPrompt = result
 	if result == empty {
 		return result, nil, nil
 	}
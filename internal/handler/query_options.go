@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"github.com/reugn/gemini-cli/gemini"
+	"github.com/reugn/gemini-cli/internal/config"
+)
+
+// QueryOptions represents configuration options for the gemini query handlers.
+// The interactive query handler shares the options with the system commands,
+// which may adjust them at runtime.
+type QueryOptions struct {
+	// Stream enables incremental rendering of the model response.
+	Stream bool
+	// Raw disables the markdown rendering of the model response.
+	Raw bool
+	// Thoughts is the display mode of the model thought summaries.
+	// The thoughts are never written in the non-interactive mode.
+	Thoughts ThoughtsMode
+	// Citations enables the inline citation markers in the grounded responses.
+	// The markers are not inserted into the streamed responses.
+	Citations bool
+	// JSON enables the structured output mode, in which the model response is
+	// a JSON value written without rendering. The streaming, thoughts and
+	// sources are not applicable in this mode.
+	JSON bool
+	// Schema is the optional JSON schema the JSON responses are validated against.
+	Schema map[string]any
+	// Usage enables the per-turn token usage footer.
+	Usage bool
+	// Pricing contains the model pricing used to estimate the request cost,
+	// keyed by the generative model name.
+	Pricing map[string]gemini.Pricing
+	// Compaction contains the chat history compaction settings.
+	Compaction config.Compaction
+	// ApprovedFunctions contains the names of the functions called without
+	// confirmation. In the non-interactive mode, only the approved functions
+	// are called.
+	ApprovedFunctions map[string]bool
+}
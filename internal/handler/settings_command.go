@@ -0,0 +1,262 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/manifoldco/promptui"
+	"github.com/reugn/gemini-cli/gemini"
+	"github.com/reugn/gemini-cli/internal/config"
+	"google.golang.org/genai"
+)
+
+var settingsScopeOptions = []string{
+	"Apply to this session",
+	"Apply and save to the configuration file",
+}
+
+// defaultHarmCategories contains the harm categories listed in the settings,
+// even if they are not configured.
+var defaultHarmCategories = []genai.HarmCategory{
+	genai.HarmCategoryHarassment,
+	genai.HarmCategoryHateSpeech,
+	genai.HarmCategorySexuallyExplicit,
+	genai.HarmCategoryDangerousContent,
+}
+
+// SettingsCommand processes the runtime settings system command, changing
+// the safety thresholds and the enabled tools of the chat session, while
+// preserving the chat history.
+// It implements the MessageHandler interface.
+type SettingsCommand struct {
+	*IO
+	session       *gemini.ChatSession
+	configuration *config.Configuration
+	opts          *QueryOptions
+	// tools contains the tool settings of the session.
+	tools []config.Tool
+}
+
+var _ MessageHandler = (*SettingsCommand)(nil)
+
+// NewSettingsCommand returns a new SettingsCommand.
+func NewSettingsCommand(io *IO, session *gemini.ChatSession, configuration *config.Configuration,
+	opts *QueryOptions) *SettingsCommand {
+	return &SettingsCommand{
+		IO:            io,
+		session:       session,
+		configuration: configuration,
+		opts:          opts,
+		tools:         slices.Clone(configuration.Data.Tools),
+	}
+}
+
+// Handle processes the runtime settings system command.
+func (h *SettingsCommand) Handle(_ string) (Response, bool) {
+	defer h.terminal.Write(h.terminalPrompt)
+	categories := h.harmCategories()
+	index, err := h.selectSetting(categories)
+	if err != nil {
+		return newErrorResponse(err), false
+	}
+
+	var (
+		result string
+		update func(*config.ApplicationData)
+	)
+	if index < len(categories) {
+		result, update, err = h.changeThreshold(categories[index])
+	} else {
+		result, update, err = h.toggleTool(index - len(categories))
+	}
+	if err != nil {
+		return newErrorResponse(err), false
+	}
+	if update == nil {
+		return dataResponse(unchangedMessage), false
+	}
+
+	save, err := h.selectScope()
+	if err != nil {
+		return newErrorResponse(err), false
+	}
+	if save {
+		if err := h.configuration.Update(update); err != nil {
+			return newErrorResponse(fmt.Errorf("failed to save settings: %w", err)), false
+		}
+		result += " Saved to the configuration file."
+	}
+
+	return dataResponse(result), false
+}
+
+// changeThreshold prompts for the blocking threshold of the harm category,
+// and applies it to the session. It returns the description of the change,
+// and the update of the configuration, which is nil if the setting is unchanged.
+func (h *SettingsCommand) changeThreshold(category genai.HarmCategory) (string,
+	func(*config.ApplicationData), error) {
+	settings := h.session.SafetySettings()
+	current := config.NewThreshold(safetyThreshold(settings, category))
+	prompt := promptui.Select{
+		Label:        fmt.Sprintf("Select %s threshold", categoryName(category)),
+		HideSelected: true,
+		Items:        config.Thresholds,
+		CursorPos:    max(slices.Index(config.Thresholds, current), 0),
+	}
+
+	i, _, err := prompt.Run()
+	if err != nil {
+		return "", nil, err
+	}
+	threshold := config.Thresholds[i]
+	if threshold == current {
+		return "", nil, nil
+	}
+
+	settings = setSafetyThreshold(settings, category, threshold.Genai())
+	if err := h.session.SetSafetySettings(settings); err != nil {
+		return "", nil, err
+	}
+
+	update := func(data *config.ApplicationData) {
+		data.SafetySettings = config.NewSafetySettings(setSafetyThreshold(
+			data.GenaiSafetySettings(), category, threshold.Genai()))
+	}
+	return fmt.Sprintf("Set the %s threshold to %s.", categoryName(category), threshold), update, nil
+}
+
+// toggleTool enables or disables the tool, and applies the change to the
+// session. It returns the description of the change, and the update of the
+// configuration.
+func (h *SettingsCommand) toggleTool(index int) (string, func(*config.ApplicationData), error) {
+	tool := &h.tools[index]
+	tool.Enabled = !tool.Enabled
+
+	if err := h.applyTool(tool); err != nil {
+		tool.Enabled = !tool.Enabled
+		return "", nil, err
+	}
+
+	name, enabled := tool.Name, tool.Enabled
+	update := func(data *config.ApplicationData) {
+		for i := range data.Tools {
+			if data.Tools[i].Name == name {
+				data.Tools[i].Enabled = enabled
+			}
+		}
+	}
+	return fmt.Sprintf("%s the %s tool.", enabledName(enabled), name), update, nil
+}
+
+// applyTool applies the tool setting to the session. The built-in tools are
+// replaced as a whole, and the function tools are registered or removed.
+// The tools cannot be enabled in the JSON output mode.
+func (h *SettingsCommand) applyTool(tool *config.Tool) error {
+	if h.opts.JSON {
+		return errors.New("tools are not available in the JSON output mode")
+	}
+	data := &config.ApplicationData{Tools: h.tools}
+	functions, err := data.Functions()
+	if err != nil {
+		return err
+	}
+
+	index := slices.IndexFunc(functions, func(function gemini.Function) bool {
+		return function.Declaration().Name == tool.Name
+	})
+	switch {
+	case index >= 0:
+		if tool.AutoApprove {
+			h.approveFunction(tool.Name)
+		}
+		return h.session.RegisterFunctions(functions[index])
+	case len(tool.Command) > 0:
+		delete(h.opts.ApprovedFunctions, tool.Name)
+		return h.session.UnregisterFunctions(tool.Name)
+	default:
+		return h.session.SetTools(data.GenaiTools())
+	}
+}
+
+// approveFunction adds the function to the functions called without confirmation.
+func (h *SettingsCommand) approveFunction(name string) {
+	if h.opts.ApprovedFunctions == nil {
+		h.opts.ApprovedFunctions = make(map[string]bool)
+	}
+	h.opts.ApprovedFunctions[name] = true
+}
+
+// harmCategories returns the harm categories of the session safety settings,
+// along with the default categories.
+func (h *SettingsCommand) harmCategories() []genai.HarmCategory {
+	categories := slices.Clone(defaultHarmCategories)
+	for _, setting := range h.session.SafetySettings() {
+		if !slices.Contains(categories, setting.Category) {
+			categories = append(categories, setting.Category)
+		}
+	}
+	return categories
+}
+
+// selectSetting returns the index of the selected setting, where the safety
+// settings of the categories precede the tools.
+func (h *SettingsCommand) selectSetting(categories []genai.HarmCategory) (int, error) {
+	settings := h.session.SafetySettings()
+	items := make([]string, 0, len(categories)+len(h.tools))
+	for _, category := range categories {
+		items = append(items, fmt.Sprintf("Safety: %s (%s)", categoryName(category),
+			thresholdName(safetyThreshold(settings, category))))
+	}
+	for _, tool := range h.tools {
+		items = append(items, fmt.Sprintf("Tool: %s (%s)", tool.Name, enabledName(tool.Enabled)))
+	}
+
+	prompt := promptui.Select{
+		Label:        "Select setting",
+		HideSelected: true,
+		Items:        items,
+		Size:         len(items),
+	}
+
+	i, _, err := prompt.Run()
+	return i, err
+}
+
+// selectScope returns true if the change should be saved to the
+// configuration file.
+func (h *SettingsCommand) selectScope() (bool, error) {
+	prompt := promptui.Select{
+		Label:        "Select scope",
+		HideSelected: true,
+		Items:        settingsScopeOptions,
+	}
+
+	i, _, err := prompt.Run()
+	return i == 1, err
+}
+
+// setSafetyThreshold returns the safety settings with the threshold of the
+// category replaced, or added if the category is not configured.
+func setSafetyThreshold(settings []*genai.SafetySetting, category genai.HarmCategory,
+	threshold genai.HarmBlockThreshold) []*genai.SafetySetting {
+	setting := &genai.SafetySetting{Category: category, Threshold: threshold}
+	i := slices.IndexFunc(settings, func(s *genai.SafetySetting) bool {
+		return s.Category == category
+	})
+	if i < 0 {
+		return append(settings, setting)
+	}
+
+	settings = slices.Clone(settings)
+	settings[i] = setting
+	return settings
+}
+
+// enabledName returns the name of the enabled state.
+func enabledName(enabled bool) string {
+	if enabled {
+		return "Enabled"
+	}
+	return "Disabled"
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/reugn/gemini-cli/gemini"
+	"google.golang.org/genai"
+)
+
+// SingleQuery processes a single non-interactive query to gemini models,
+// writing the response to the underlying writer.
+type SingleQuery struct {
+	writer   io.Writer
+	session  *gemini.ChatSession
+	renderer *glamour.TermRenderer
+	opts     QueryOptions
+}
+
+// NewSingleQuery returns a new SingleQuery.
+func NewSingleQuery(writer io.Writer, session *gemini.ChatSession, opts QueryOptions,
+	rendererOptions RendererOptions) (*SingleQuery, error) {
+	renderer, err := newResponseRenderer(opts, rendererOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// the user cannot be prompted; only the approved functions are called
+	session.SetFunctionCallConfirmation(func(call *genai.FunctionCall) bool {
+		return opts.ApprovedFunctions[call.Name]
+	})
+	// the retries are reported to stderr, keeping the output clean
+	session.SetRetryNotification(func(retry int, delay time.Duration, _ error) {
+		_, _ = fmt.Fprintf(os.Stderr, "%s %s\n",
+			retryMessage(retry, session.RetryPolicy().MaxRetries), delay.Round(time.Second))
+	})
+
+	return &SingleQuery{
+		writer:   writer,
+		session:  session,
+		renderer: renderer,
+		opts:     opts,
+	}, nil
+}
+
+// Run sends the message to the model and writes the response.
+// The files referenced using the inline attachment syntax are sent along with
+// the message. It returns an error if the request fails, if the response is
+// blocked, or if the response is not valid JSON in the JSON output mode.
+func (q *SingleQuery) Run(message string) error {
+	attachments, err := parseInlineAttachments(message)
+	if err != nil {
+		return err
+	}
+	if err := gemini.CheckAttachmentSize(attachments); err != nil {
+		return err
+	}
+
+	ctx, cancel := newRequestContext()
+	defer cancel()
+
+	if q.opts.Stream && !q.opts.JSON {
+		return q.runStream(ctx, message, attachments)
+	}
+
+	response, err := q.session.SendMessage(ctx, message, attachments...)
+	if err != nil {
+		return err
+	}
+
+	issue := candidateIssue(firstCandidate(response), q.session.SafetySettings())
+	if q.opts.JSON {
+		text, err := jsonResponse(response, q.opts.Schema)
+		return errors.Join(q.write(text), err, reportIssue(issue))
+	}
+
+	text, _ := responseMarkdown(response, q.session.TurnGroundingMetadata(), q.opts.Citations)
+	rendered, err := renderResponse(q.renderer, text)
+	if err != nil {
+		return fmt.Errorf("failed to format response: %w", err)
+	}
+
+	return errors.Join(q.write(rendered), reportIssue(issue))
+}
+
+// runStream sends the message to the model using a streaming request,
+// writing the response incrementally.
+func (q *SingleQuery) runStream(ctx context.Context, message string,
+	attachments []*gemini.Attachment) error {
+	var (
+		lastChunk string
+		writeErr  error
+	)
+	stream := newStreamRenderer(q.renderer, func(chunk string) {
+		if writeErr == nil {
+			_, writeErr = io.WriteString(q.writer, chunk)
+			lastChunk = chunk
+		}
+	})
+
+	var (
+		sources   sourceList
+		candidate *genai.Candidate
+	)
+	for response, err := range q.session.SendMessageStream(ctx, message, attachments...) {
+		if err != nil {
+			return err
+		}
+		if c := firstCandidate(response); c != nil && c.FinishReason != "" {
+			candidate = c
+		}
+		sources.addResponse(response)
+		if err := stream.Write(responseText(response)); err != nil {
+			return fmt.Errorf("failed to format response: %w", err)
+		}
+	}
+
+	if err := stream.Write(formatSources(sources.list)); err != nil {
+		return fmt.Errorf("failed to format response: %w", err)
+	}
+	if err := stream.Flush(); err != nil {
+		return fmt.Errorf("failed to format response: %w", err)
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+
+	issue := candidateIssue(candidate, q.session.SafetySettings())
+	if !strings.HasSuffix(lastChunk, "\n") {
+		return errors.Join(q.write(""), reportIssue(issue))
+	}
+	return reportIssue(issue)
+}
+
+// reportIssue returns an error if the response is blocked. The other issues,
+// such as a truncated answer, are reported to the standard error.
+func reportIssue(issue *responseIssue) error {
+	switch {
+	case issue == nil:
+		return nil
+	case issue.blocked:
+		return errors.New(issue.message)
+	default:
+		_, _ = fmt.Fprintln(os.Stderr, issue.message)
+		return nil
+	}
+}
+
+// write writes the output terminated with a newline.
+func (q *SingleQuery) write(output string) error {
+	if !strings.HasSuffix(output, "\n") {
+		output += "\n"
+	}
+	_, err := io.WriteString(q.writer, output)
+	return err
+}
@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/glamour"
+	"google.golang.org/genai"
+)
+
+// Source represents a source used to ground the model response.
+type Source struct {
+	Title string
+	URI   string
+}
+
+// Sources holds the sources of the last model response.
+// The zero value is ready to use.
+type Sources struct {
+	sources []Source
+}
+
+// Set replaces the sources.
+func (s *Sources) Set(sources []Source) {
+	s.sources = sources
+}
+
+// List returns a copy of the sources slice.
+func (s *Sources) List() []Source {
+	return slices.Clone(s.sources)
+}
+
+// SourcesCommand processes the response sources system command.
+// It implements the MessageHandler interface.
+type SourcesCommand struct {
+	*IO
+	sources  *Sources
+	renderer *glamour.TermRenderer
+}
+
+var _ MessageHandler = (*SourcesCommand)(nil)
+
+// NewSourcesCommand returns a new SourcesCommand.
+func NewSourcesCommand(io *IO, sources *Sources, opts RendererOptions) (*SourcesCommand, error) {
+	renderer, err := opts.newTermRenderer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate terminal renderer: %w", err)
+	}
+
+	return &SourcesCommand{
+		IO:       io,
+		sources:  sources,
+		renderer: renderer,
+	}, nil
+}
+
+// Handle processes the response sources system command.
+func (h *SourcesCommand) Handle(_ string) (Response, bool) {
+	sources := h.sources.List()
+	if len(sources) == 0 {
+		return dataResponse("The last response has no sources."), false
+	}
+
+	rendered, err := h.renderer.Render(formatSources(sources))
+	if err != nil {
+		return newErrorResponse(fmt.Errorf("failed to format sources: %w", err)), false
+	}
+
+	return dataResponse(rendered), false
+}
+
+// responseMarkdown returns the response text followed by the numbered sources,
+// and the sources. The sources include the ones used to ground the responses
+// of the preceding function call rounds of the turn, given by the grounding
+// metadata. If enabled, the citation markers are inserted into the text.
+func responseMarkdown(response *genai.GenerateContentResponse, grounding []*genai.GroundingMetadata,
+	citations bool) (string, []Source) {
+	var sources sourceList
+	candidate := firstCandidate(response)
+	for _, metadata := range grounding {
+		if candidate == nil || metadata != candidate.GroundingMetadata {
+			sources.addGrounding(metadata)
+		}
+	}
+	numbers := sources.addResponse(response)
+	if citations && candidate != nil && candidate.Content != nil {
+		response = citedResponse(response, numbers)
+	}
+
+	return responseText(response) + formatSources(sources.list), sources.list
+}
+
+// sourceList is the list of the numbered sources of a chat turn, which are
+// deduplicated by URI. The zero value is ready to use.
+type sourceList struct {
+	list    []Source
+	indices map[string]int
+}
+
+// add appends the source, unless it is already listed, and returns its number
+// starting at 1.
+func (l *sourceList) add(source Source) int {
+	if i, ok := l.indices[source.URI]; ok {
+		return i + 1
+	}
+	if l.indices == nil {
+		l.indices = make(map[string]int)
+	}
+	l.indices[source.URI] = len(l.list)
+	l.list = append(l.list, source)
+	return len(l.list)
+}
+
+// addGrounding adds the web sources of the grounding metadata, and returns
+// their numbers keyed by the grounding chunk index.
+func (l *sourceList) addGrounding(metadata *genai.GroundingMetadata) map[int32]int {
+	numbers := make(map[int32]int)
+	if metadata == nil {
+		return numbers
+	}
+	for i, chunk := range metadata.GroundingChunks {
+		if chunk.Web != nil && chunk.Web.URI != "" {
+			numbers[int32(i)] = l.add(Source{Title: chunk.Web.Title, URI: chunk.Web.URI})
+		}
+	}
+	return numbers
+}
+
+// addResponse adds the web sources used to ground the first response candidate,
+// and the URLs retrieved by the URL context tool. The numbers of the web sources
+// are returned keyed by the grounding chunk index.
+func (l *sourceList) addResponse(response *genai.GenerateContentResponse) map[int32]int {
+	candidate := firstCandidate(response)
+	if candidate == nil {
+		return nil
+	}
+
+	numbers := l.addGrounding(candidate.GroundingMetadata)
+	if metadata := candidate.URLContextMetadata; metadata != nil {
+		for _, url := range metadata.URLMetadata {
+			if url.URLRetrievalStatus == genai.URLRetrievalStatusSuccess {
+				l.add(Source{URI: url.RetrievedURL})
+			}
+		}
+	}
+	return numbers
+}
+
+// citedResponse returns a copy of the response, with the citation markers
+// inserted into the text parts of the first candidate.
+func citedResponse(response *genai.GenerateContentResponse, numbers map[int32]int) *genai.GenerateContentResponse {
+	candidate := *response.Candidates[0]
+	content := *candidate.Content
+	content.Parts = insertCitations(content.Parts, candidate.GroundingMetadata, numbers)
+	candidate.Content = &content
+
+	cited := *response
+	cited.Candidates = slices.Clone(response.Candidates)
+	cited.Candidates[0] = &candidate
+	return &cited
+}
+
+// insertCitations returns the parts with the numbered citation markers inserted
+// after the text segments supported by the grounding sources. The segments are
+// located by their byte offsets in the text of the parts.
+func insertCitations(parts []*genai.Part, metadata *genai.GroundingMetadata,
+	numbers map[int32]int) []*genai.Part {
+	if metadata == nil {
+		return parts
+	}
+
+	// the cited source numbers keyed by the part index and the segment end
+	type position struct {
+		part, end int
+	}
+	cited := make(map[position][]int)
+	for _, support := range metadata.GroundingSupports {
+		segment := support.Segment
+		if segment == nil || segment.PartIndex < 0 || int(segment.PartIndex) >= len(parts) {
+			continue
+		}
+		text := parts[segment.PartIndex].Text
+		end := int(segment.EndIndex)
+		if end <= 0 || end > len(text) || (end < len(text) && !utf8.RuneStart(text[end])) {
+			continue
+		}
+
+		at := position{part: int(segment.PartIndex), end: end}
+		for _, index := range support.GroundingChunkIndices {
+			if number, ok := numbers[index]; ok && !slices.Contains(cited[at], number) {
+				cited[at] = append(cited[at], number)
+			}
+		}
+	}
+	if len(cited) == 0 {
+		return parts
+	}
+
+	parts = slices.Clone(parts)
+	for i, part := range parts {
+		var ends []int
+		for at := range cited {
+			if at.part == i {
+				ends = append(ends, at.end)
+			}
+		}
+		if len(ends) == 0 {
+			continue
+		}
+		slices.Sort(ends)
+
+		var b strings.Builder
+		offset := 0
+		for _, end := range ends {
+			b.WriteString(part.Text[offset:end])
+			offset = end
+			for _, number := range cited[position{part: i, end: end}] {
+				fmt.Fprintf(&b, "[%d]", number)
+			}
+		}
+		b.WriteString(part.Text[offset:])
+
+		citedPart := *part
+		citedPart.Text = b.String()
+		parts[i] = &citedPart
+	}
+
+	return parts
+}
+
+// formatSources returns the markdown list of the numbered source links.
+func formatSources(sources []Source) string {
+	if len(sources) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\n**Sources**\n\n")
+	for i, source := range sources {
+		title := cmp.Or(source.Title, source.URI)
+		fmt.Fprintf(&b, "%d. [%s](%s)\n", i+1, title, source.URI)
+	}
+	return b.String()
+}
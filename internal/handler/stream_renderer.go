@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/glamour"
+)
+
+// streamRenderer renders markdown text received in chunks. Every completed
+// block is rendered and written as soon as it is available, so that partial
+// output is visible before the whole response is received.
+// If the renderer is nil, the chunks are written as is.
+type streamRenderer struct {
+	renderer *glamour.TermRenderer
+	write    func(string)
+
+	pending  string
+	rendered bool
+}
+
+// newStreamRenderer returns a new streamRenderer.
+func newStreamRenderer(renderer *glamour.TermRenderer, write func(string)) *streamRenderer {
+	return &streamRenderer{
+		renderer: renderer,
+		write:    write,
+	}
+}
+
+// Write appends the text chunk and renders all the completed blocks.
+func (r *streamRenderer) Write(text string) error {
+	if r.renderer == nil {
+		if text != "" {
+			r.write(text)
+		}
+		return nil
+	}
+
+	r.pending += text
+	for {
+		end := completedBlockEnd(r.pending)
+		if end < 0 {
+			return nil
+		}
+
+		if err := r.render(r.pending[:end]); err != nil {
+			return err
+		}
+		r.pending = r.pending[end:]
+	}
+}
+
+// Flush renders the remaining pending text.
+func (r *streamRenderer) Flush() error {
+	defer func() { r.pending = "" }()
+	if strings.TrimSpace(r.pending) == "" {
+		return nil
+	}
+	return r.render(r.pending)
+}
+
+func (r *streamRenderer) render(block string) error {
+	rendered, err := r.renderer.Render(block)
+	if err != nil {
+		return err
+	}
+
+	if r.rendered {
+		// blocks are already separated by the trailing newlines
+		// of the previous block
+		rendered = strings.TrimPrefix(rendered, "\n")
+	}
+	r.rendered = true
+
+	r.write(rendered)
+	return nil
+}
+
+// completedBlockEnd returns the end position of the first completed markdown
+// block in the text, or -1 if there is none. A block is considered completed
+// when it is followed by a blank line outside a fenced code block, and the next
+// line does not continue it (e.g., an indented line or a list item).
+func completedBlockEnd(text string) int {
+	var (
+		fence      string // the opening fence of the current code block
+		hasContent bool
+		blankEnd   = -1
+		offset     int
+	)
+	for {
+		i := strings.IndexByte(text[offset:], '\n')
+		if i < 0 {
+			return -1
+		}
+		line := text[offset : offset+i]
+		next := offset + i + 1
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case fence == "" && trimmed == "":
+			if hasContent && blankEnd < 0 {
+				blankEnd = next
+			}
+			offset = next
+			continue
+		case blankEnd > 0 && !continuesBlock(line):
+			return blankEnd
+		case fence == "":
+			fence = openingFence(trimmed)
+		case closesFence(trimmed, fence):
+			fence = ""
+		}
+
+		hasContent = true
+		blankEnd = -1
+		offset = next
+	}
+}
+
+// openingFence returns the code fence opening a fenced code block, i.e. a run
+// of at least three backticks or tildes, or an empty string if the trimmed
+// line does not open one. The info string of a backtick fence may not contain
+// backticks.
+func openingFence(trimmed string) string {
+	fence := codeFence(trimmed)
+	if fence == "" || (fence[0] == '`' && strings.ContainsRune(trimmed[len(fence):], '`')) {
+		return ""
+	}
+	return fence
+}
+
+// closesFence reports whether the trimmed line closes the code block opened
+// by the fence. The closing fence must consist of the same character, be at
+// least as long as the opening one, and not be followed by an info string.
+func closesFence(trimmed, fence string) bool {
+	closing := codeFence(trimmed)
+	return closing != "" && closing[0] == fence[0] && len(closing) >= len(fence) &&
+		len(closing) == len(trimmed)
+}
+
+// codeFence returns the leading run of backticks or tildes of the trimmed line,
+// if it is at least three characters long, or an empty string otherwise.
+func codeFence(trimmed string) string {
+	if trimmed == "" || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return ""
+	}
+	fence := trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+	if len(fence) < 3 {
+		return ""
+	}
+	return fence
+}
+
+// continuesBlock reports whether the line may continue the preceding block
+// despite the blank line separation.
+func continuesBlock(line string) bool {
+	if line[0] == ' ' || line[0] == '\t' {
+		return true
+	}
+
+	switch {
+	case strings.HasPrefix(line, "- "), strings.HasPrefix(line, "* "),
+		strings.HasPrefix(line, "+ "):
+		return true
+	}
+
+	digits := strings.TrimLeftFunc(line, unicode.IsDigit)
+	return len(digits) < len(line) &&
+		(strings.HasPrefix(digits, ". ") || strings.HasPrefix(digits, ") "))
+}
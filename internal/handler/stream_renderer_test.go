@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/glamour"
+)
+
+func TestCompletedBlockEnd(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		// block is the expected completed block, or "-" if there is none
+		block string
+	}{
+		{"empty", "", "-"},
+		{"incomplete line", "text", "-"},
+		{"no blank line", "line 1\nline 2\n", "-"},
+		{"paragraph", "line 1\n\nline 2\n", "line 1\n\n"},
+		{"leading blank lines", "\n\nline 1\n\nline 2\n", "\n\nline 1\n\n"},
+		{"undecided next line", "line 1\n\nline 2", "-"},
+		{"indented continuation", "- item\n\n  more\n\nnext\n", "- item\n\n  more\n\n"},
+		{"list continuation", "- item 1\n\n- item 2\n\nnext\n", "- item 1\n\n- item 2\n\n"},
+		{"ordered list continuation", "1. item 1\n\n2) item 2\n\nnext\n", "1. item 1\n\n2) item 2\n\n"},
+
+		{"backtick fence", "```go\na\n\nb\n```\n\nnext\n", "```go\na\n\nb\n```\n\n"},
+		{"tilde fence", "~~~\na\n\nb\n~~~\n\nnext\n", "~~~\na\n\nb\n~~~\n\n"},
+		{"unclosed fence", "```\na\n\nb\n", "-"},
+		{"longer closing fence", "```\na\n\n`````\n\nnext\n", "```\na\n\n`````\n\n"},
+		{"shorter closing fence", "````\na\n```\n\nb\n````\n\nnext\n", "````\na\n```\n\nb\n````\n\n"},
+		{"mismatched closing fence", "```\na\n~~~\n\nb\n```\n\nnext\n", "```\na\n~~~\n\nb\n```\n\n"},
+		{"closing fence with info", "```\na\n```go\n\nb\n```\n\nnext\n", "```\na\n```go\n\nb\n```\n\n"},
+		{"backtick info with backtick", "``` a`b\n\nnext\n", "``` a`b\n\n"},
+		{"tilde info with backtick", "~~~ a`b\n\n~~~\n\nnext\n", "~~~ a`b\n\n~~~\n\n"},
+		{"indented fence", "  ```\na\n\n  ```\n\nnext\n", "  ```\na\n\n  ```\n\n"},
+		{"two backticks", "``\na\n\nnext\n", "``\na\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end := completedBlockEnd(tt.text)
+			if tt.block == "-" {
+				if end >= 0 {
+					t.Errorf("unexpected block %q", tt.text[:end])
+				}
+				return
+			}
+			if end < 0 {
+				t.Fatalf("expected block %q", tt.block)
+			}
+			if block := tt.text[:end]; block != tt.block {
+				t.Errorf("block = %q, want %q", block, tt.block)
+			}
+		})
+	}
+}
+
+func TestStreamRendererChunks(t *testing.T) {
+	renderer, err := glamour.NewTermRenderer(glamour.WithStandardStyle("notty"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		text   string
+		blocks int
+	}{
+		{"paragraphs", "First paragraph.\n\nSecond paragraph.\n", 2},
+		{"backtick fence", "Code:\n\n```go\nfunc a() {}\n\nfunc b() {}\n```\n\nDone.\n", 3},
+		{"tilde fence", "Code:\n\n~~~\na\n\n```\nb\n~~~\n\nDone.\n", 3},
+		{"longer closing fence", "```\na\n\nb\n`````\n\nDone.\n", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var whole []string
+			stream := newStreamRenderer(renderer, func(s string) { whole = append(whole, s) })
+			writeChunks(t, stream, tt.text)
+			if len(whole) != tt.blocks {
+				t.Fatalf("rendered %d blocks, want %d: %q", len(whole), tt.blocks, whole)
+			}
+
+			// the output does not depend on where the chunks are split,
+			// including the middle of a code fence
+			for i := 1; i < len(tt.text); i++ {
+				var split []string
+				stream := newStreamRenderer(renderer, func(s string) { split = append(split, s) })
+				writeChunks(t, stream, tt.text[:i], tt.text[i:])
+				if strings.Join(split, "") != strings.Join(whole, "") || len(split) != len(whole) {
+					t.Errorf("split at %d (%q): rendered %q, want %q", i, tt.text[:i], split, whole)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamRendererPlain(t *testing.T) {
+	var written []string
+	stream := newStreamRenderer(nil, func(s string) { written = append(written, s) })
+	writeChunks(t, stream, "``", "`\na", "", "\n")
+	if want := []string{"``", "`\na", "\n"}; strings.Join(written, "|") != strings.Join(want, "|") {
+		t.Errorf("written = %q, want %q", written, want)
+	}
+}
+
+func writeChunks(t *testing.T, stream *streamRenderer, chunks ...string) {
+	t.Helper()
+	for _, chunk := range chunks {
+		if err := stream.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stream.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
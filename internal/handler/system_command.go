@@ -25,19 +25,46 @@ var _ MessageHandler = (*SystemCommand)(nil)
 
 // NewSystemCommand returns a new SystemCommand.
 func NewSystemCommand(io *IO, session *gemini.ChatSession, configuration *config.Configuration,
-	modelName string, rendererOptions RendererOptions) (*SystemCommand, error) {
+	attachments *Attachments, sources *Sources, opts SystemCommandOptions,
+	rendererOptions RendererOptions) (*SystemCommand, error) {
 	helpCommandHandler, err := NewHelpCommand(io, rendererOptions)
 	if err != nil {
 		return nil, err
 	}
 
+	usageCommandHandler, err := NewUsageCommand(io, session, opts.QueryOptions, rendererOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	sourcesCommandHandler, err := NewSourcesCommand(io, sources, rendererOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	toolsCommandHandler, err := NewToolsCommand(io, session, opts.MCPServers, rendererOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	systemPromptHandler := NewSystemPromptCommand(io, session, configuration.Data, opts.GenerationConfig)
+
 	handlers := map[string]MessageHandler{
 		cli.SystemCmdHelp:            helpCommandHandler,
 		cli.SystemCmdQuit:            NewQuitCommand(io),
-		cli.SystemCmdSelectPrompt:    NewSystemPromptCommand(io, session, configuration.Data),
+		cli.SystemCmdSelectPrompt:    systemPromptHandler,
 		cli.SystemCmdSelectInputMode: NewInputModeCommand(io),
-		cli.SystemCmdModel:           NewModelCommand(io, session, modelName),
-		cli.SystemCmdHistory:         NewHistoryCommand(io, session, configuration),
+		cli.SystemCmdModel:           NewModelCommand(io, session),
+		cli.SystemCmdGeneration:      NewGenerationCommand(io, session),
+		cli.SystemCmdThoughts:        NewThoughtsCommand(io, opts.QueryOptions),
+		cli.SystemCmdHistory:         NewHistoryCommand(io, session, configuration.Data, opts.HistoryStore),
+		cli.SystemCmdAttach:          NewAttachCommand(io, attachments),
+		cli.SystemCmdUsage:           usageCommandHandler,
+		cli.SystemCmdTokens:          NewTokensCommand(io, session),
+		cli.SystemCmdCompact:         NewCompactCommand(io, session, opts.QueryOptions),
+		cli.SystemCmdTools:           toolsCommandHandler,
+		cli.SystemCmdSources:         sourcesCommandHandler,
+		cli.SystemCmdSettings:        NewSettingsCommand(io, session, configuration, opts.QueryOptions),
 	}
 
 	return &SystemCommand{
@@ -52,13 +79,8 @@ func (s *SystemCommand) Handle(message string) (Response, bool) {
 		return newErrorResponse(fmt.Errorf("system command mismatch")), false
 	}
 
-	var args string
-	t := strings.SplitN(message, " ", 2)
-	if len(t) == 2 {
-		args = t[1]
-	}
-
-	systemHandler, ok := s.handlers[message[1:]]
+	command, args, _ := strings.Cut(message, " ")
+	systemHandler, ok := s.handlers[command[1:]]
 	if !ok {
 		return newErrorResponse(fmt.Errorf("unknown system command")), false
 	}
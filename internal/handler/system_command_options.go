@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"github.com/reugn/gemini-cli/gemini"
+	"github.com/reugn/gemini-cli/internal/history"
+	"github.com/reugn/gemini-cli/internal/mcp"
+)
+
+// SystemCommandOptions represents configuration options for the system command handlers.
+type SystemCommandOptions struct {
+	// GenerationConfig contains the command line generation parameters, which
+	// take precedence over the configured ones.
+	GenerationConfig *gemini.GenerationConfig
+	// QueryOptions are the query handler options adjusted by the system commands.
+	QueryOptions *QueryOptions
+	// MCPServers contains the configured MCP servers.
+	MCPServers mcp.Servers
+	// HistoryStore is the store of the chat history records.
+	HistoryStore *history.Store
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/reugn/gemini-cli/internal/terminal/color"
+	"google.golang.org/genai"
+)
+
+// collapsedThoughtsLines is the number of thought lines displayed
+// in the collapsed mode.
+const collapsedThoughtsLines = 3
+
+// ThoughtsMode represents the display mode of the model thought summaries.
+type ThoughtsMode string
+
+const (
+	// ThoughtsHidden hides the model thoughts.
+	ThoughtsHidden ThoughtsMode = "hidden"
+	// ThoughtsCollapsed displays the first lines of the model thoughts.
+	ThoughtsCollapsed ThoughtsMode = "collapsed"
+	// ThoughtsExpanded displays the model thoughts in full.
+	ThoughtsExpanded ThoughtsMode = "expanded"
+)
+
+// ThoughtsModes lists the supported thoughts display modes.
+var ThoughtsModes = []ThoughtsMode{
+	ThoughtsHidden,
+	ThoughtsCollapsed,
+	ThoughtsExpanded,
+}
+
+// responseThoughts returns the concatenated text of the response thought parts.
+func responseThoughts(response *genai.GenerateContentResponse) string {
+	var b strings.Builder
+	for _, candidate := range response.Candidates {
+		if candidate.Content == nil {
+			continue
+		}
+		for _, part := range candidate.Content.Parts {
+			if part.Thought {
+				b.WriteString(part.Text)
+			}
+		}
+	}
+	return b.String()
+}
+
+// formatThoughts returns the dimmed thoughts section, displayed according
+// to the mode. An empty string is returned if there is nothing to display.
+func formatThoughts(thoughts string, mode ThoughtsMode) string {
+	if mode == ThoughtsHidden {
+		return ""
+	}
+
+	var lines []string
+	for line := range strings.Lines(thoughts) {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\nThoughts:\n")
+	for i, line := range lines {
+		if mode == ThoughtsCollapsed && i == collapsedThoughtsLines {
+			fmt.Fprintf(&b, "  ... (%d more lines)\n", len(lines)-i)
+			break
+		}
+		fmt.Fprintf(&b, "  %s\n", line)
+	}
+
+	return color.Dim(b.String())
+}
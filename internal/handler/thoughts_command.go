@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/manifoldco/promptui"
+)
+
+// ThoughtsCommand processes the model thoughts display system command.
+// It implements the MessageHandler interface.
+type ThoughtsCommand struct {
+	*IO
+	queryOptions *QueryOptions
+}
+
+var _ MessageHandler = (*ThoughtsCommand)(nil)
+
+// NewThoughtsCommand returns a new ThoughtsCommand.
+func NewThoughtsCommand(io *IO, queryOptions *QueryOptions) *ThoughtsCommand {
+	return &ThoughtsCommand{
+		IO:           io,
+		queryOptions: queryOptions,
+	}
+}
+
+// Handle processes the model thoughts display system command.
+func (h *ThoughtsCommand) Handle(_ string) (Response, bool) {
+	defer h.terminal.Write(h.terminalPrompt)
+	mode, err := h.selectThoughtsMode()
+	if err != nil {
+		return newErrorResponse(err), false
+	}
+
+	if h.queryOptions.Thoughts == mode {
+		return dataResponse(unchangedMessage), false
+	}
+
+	h.queryOptions.Thoughts = mode
+	return dataResponse(fmt.Sprintf("Switched to %q thoughts display mode.", mode)), false
+}
+
+// selectThoughtsMode returns the selected thoughts display mode.
+func (h *ThoughtsCommand) selectThoughtsMode() (ThoughtsMode, error) {
+	prompt := promptui.Select{
+		Label:        "Select thoughts display mode",
+		HideSelected: true,
+		Items:        ThoughtsModes,
+		CursorPos:    slices.Index(ThoughtsModes, h.queryOptions.Thoughts),
+	}
+
+	i, _, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+
+	return ThoughtsModes[i], nil
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/reugn/gemini-cli/gemini"
+)
+
+// TokensCommand processes the context size system command.
+// It implements the MessageHandler interface.
+type TokensCommand struct {
+	*IO
+	session *gemini.ChatSession
+}
+
+var _ MessageHandler = (*TokensCommand)(nil)
+
+// NewTokensCommand returns a new TokensCommand.
+func NewTokensCommand(io *IO, session *gemini.ChatSession) *TokensCommand {
+	return &TokensCommand{
+		IO:      io,
+		session: session,
+	}
+}
+
+// Handle processes the context size system command.
+func (h *TokensCommand) Handle(_ string) (Response, bool) {
+	ctx, cancel := newRequestContext()
+	defer cancel()
+
+	h.terminal.Spinner.Start()
+	defer h.terminal.Spinner.Stop()
+
+	size, err := h.session.CountTokens(ctx, "")
+	if err != nil {
+		return requestErrorResponse(err), false
+	}
+
+	return dataResponse(fmt.Sprintf("The chat context of %s takes %s.",
+		h.session.Model(), formatContextSize(size))), false
+}
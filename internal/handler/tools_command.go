@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/reugn/gemini-cli/gemini"
+	"github.com/reugn/gemini-cli/internal/mcp"
+)
+
+// ToolsCommand processes the tools system command.
+// It implements the MessageHandler interface.
+type ToolsCommand struct {
+	*IO
+	session  *gemini.ChatSession
+	servers  mcp.Servers
+	renderer *glamour.TermRenderer
+}
+
+var _ MessageHandler = (*ToolsCommand)(nil)
+
+// NewToolsCommand returns a new ToolsCommand.
+func NewToolsCommand(io *IO, session *gemini.ChatSession, servers mcp.Servers,
+	opts RendererOptions) (*ToolsCommand, error) {
+	renderer, err := opts.newTermRenderer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate terminal renderer: %w", err)
+	}
+
+	return &ToolsCommand{
+		IO:       io,
+		session:  session,
+		servers:  servers,
+		renderer: renderer,
+	}, nil
+}
+
+// Handle processes the tools system command.
+// It lists the local functions, and the configured MCP servers with their tools.
+func (h *ToolsCommand) Handle(_ string) (Response, bool) {
+	var b strings.Builder
+	b.WriteString("# Function tools\n")
+
+	b.WriteString("## Local functions\n")
+	var local int
+	for _, function := range h.session.Functions() {
+		if _, ok := function.(*mcp.Function); ok {
+			continue
+		}
+		writeToolItem(&b, function.Declaration().Name, function.Declaration().Description)
+		local++
+	}
+	if local == 0 {
+		fmt.Fprintf(&b, "%s\n", empty)
+	}
+
+	b.WriteString("## MCP servers\n")
+	if len(h.servers) == 0 {
+		fmt.Fprintf(&b, "%s\n", empty)
+	}
+	for _, server := range h.servers {
+		fmt.Fprintf(&b, "### %s\n", server.Name)
+		if server.Err != nil {
+			fmt.Fprintf(&b, "Failed to connect: %s\n", server.Err)
+			continue
+		}
+
+		info := server.Client().Server()
+		fmt.Fprintf(&b, "Connected to %s %s.\n\n", info.Name, info.Version)
+		for _, function := range server.Functions() {
+			writeToolItem(&b, function.Declaration().Name, function.Tool().Description)
+		}
+	}
+
+	rendered, err := h.renderer.Render(b.String())
+	if err != nil {
+		return newErrorResponse(fmt.Errorf("failed to format tools: %w", err)), false
+	}
+
+	return dataResponse(rendered), false
+}
+
+// writeToolItem writes the tool list item with the first line of its description.
+func writeToolItem(b *strings.Builder, name, description string) {
+	description, _, _ = strings.Cut(strings.TrimSpace(description), "\n")
+	if description == "" {
+		fmt.Fprintf(b, "* `%s`\n", name)
+		return
+	}
+	fmt.Fprintf(b, "* `%s` - %s\n", name, description)
+}
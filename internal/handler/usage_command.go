@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/reugn/gemini-cli/gemini"
+	"github.com/reugn/gemini-cli/internal/terminal/color"
+)
+
+const (
+	usageFooterOn  = "on"
+	usageFooterOff = "off"
+)
+
+// UsageCommand processes the token usage system command.
+// It implements the MessageHandler interface.
+type UsageCommand struct {
+	*IO
+	session      *gemini.ChatSession
+	queryOptions *QueryOptions
+	renderer     *glamour.TermRenderer
+}
+
+var _ MessageHandler = (*UsageCommand)(nil)
+
+// NewUsageCommand returns a new UsageCommand.
+func NewUsageCommand(io *IO, session *gemini.ChatSession, queryOptions *QueryOptions,
+	opts RendererOptions) (*UsageCommand, error) {
+	renderer, err := opts.newTermRenderer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate terminal renderer: %w", err)
+	}
+
+	return &UsageCommand{
+		IO:           io,
+		session:      session,
+		queryOptions: queryOptions,
+		renderer:     renderer,
+	}, nil
+}
+
+// Handle processes the token usage system command.
+// Without arguments, the cumulative session usage is displayed; the "on" and
+// "off" arguments toggle the per-turn usage footer.
+func (h *UsageCommand) Handle(message string) (Response, bool) {
+	switch strings.TrimSpace(message) {
+	case "":
+		return h.handleSessionUsage(), false
+	case usageFooterOn:
+		h.queryOptions.Usage = true
+		return dataResponse("Enabled the per-turn usage footer."), false
+	case usageFooterOff:
+		h.queryOptions.Usage = false
+		return dataResponse("Disabled the per-turn usage footer."), false
+	default:
+		return newErrorResponse(fmt.Errorf("unsupported argument: %s", message)), false
+	}
+}
+
+// handleSessionUsage handles the cumulative session usage request.
+func (h *UsageCommand) handleSessionUsage() Response {
+	usage := h.session.Usage()
+	if len(usage) == 0 {
+		return dataResponse("No requests have been made in this session.")
+	}
+
+	var b strings.Builder
+	b.WriteString("# Session token usage\n")
+	b.WriteString("| Model | Requests | Prompt | Cached | Output | Thoughts | Total | Cost (USD) |\n")
+	b.WriteString("|---|---|---|---|---|---|---|---|\n")
+	for _, model := range slices.Sorted(maps.Keys(usage)) {
+		modelUsage := usage[model]
+		fmt.Fprintf(&b, "| %s | %d | %d | %d | %d | %d | %d | %s |\n", model,
+			modelUsage.Requests, modelUsage.PromptTokens+modelUsage.ToolUsePromptTokens,
+			modelUsage.CachedTokens, modelUsage.CandidatesTokens, modelUsage.ThoughtsTokens,
+			modelUsage.TotalTokens, h.formatCost(model, &modelUsage))
+	}
+
+	rendered, err := h.renderer.Render(b.String())
+	if err != nil {
+		return newErrorResponse(fmt.Errorf("failed to format usage: %w", err))
+	}
+
+	return dataResponse(rendered)
+}
+
+// formatCost returns the estimated cost of the model usage, or "n/a" if the
+// model pricing is not configured.
+func (h *UsageCommand) formatCost(model string, usage *gemini.Usage) string {
+	pricing, ok := gemini.ModelPricing(h.queryOptions.Pricing, model)
+	if !ok {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.4f", pricing.Cost(usage))
+}
+
+// formatUsageFooter returns the dimmed per-turn usage footer, containing the
+// token counts, the request latency, and the estimated cost if the model pricing
+// is available.
+func formatUsageFooter(usage *gemini.Usage, latency time.Duration, pricing *gemini.Pricing) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "tokens: prompt %d", usage.PromptTokens+usage.ToolUsePromptTokens)
+	if usage.CachedTokens > 0 {
+		fmt.Fprintf(&b, " (cached %d)", usage.CachedTokens)
+	}
+	fmt.Fprintf(&b, ", output %d", usage.CandidatesTokens)
+	if usage.ThoughtsTokens > 0 {
+		fmt.Fprintf(&b, ", thoughts %d", usage.ThoughtsTokens)
+	}
+	fmt.Fprintf(&b, " | %s", latency.Round(time.Millisecond))
+	if pricing != nil {
+		fmt.Fprintf(&b, " | ~$%.6f", pricing.Cost(usage))
+	}
+
+	return color.Dim("  "+b.String()) + "\n"
+}
@@ -0,0 +1,251 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/reugn/gemini-cli/gemini"
+)
+
+const (
+	journalDir       = "autosave"
+	journalExtension = ".jsonl"
+	// maxJournals is the number of the most recent autosave journals kept
+	// in the store.
+	maxJournals = 20
+)
+
+// ErrNoJournal is returned when there are no autosaved sessions to restore.
+var ErrNoJournal = errors.New("no autosaved chat session")
+
+// journalRecord is a line of the autosave journal. A reset record replaces
+// the state of the session, and the other records append the messages to
+// its history.
+type journalRecord struct {
+	Time              time.Time                     `json:"time"`
+	Reset             bool                          `json:"reset,omitempty"`
+	Model             string                        `json:"model,omitempty"`
+	SystemPrompt      string                        `json:"system_prompt,omitempty"`
+	SystemInstruction *gemini.SerializableContent   `json:"system_instruction,omitempty"`
+	GenerationConfig  *gemini.GenerationConfig      `json:"generation_config,omitempty"`
+	Contents          []*gemini.SerializableContent `json:"contents"`
+}
+
+// Journal is the autosave journal of a chat session. Each recorded turn is
+// appended to the journal file, so that the session can be restored after
+// the application exits unexpectedly.
+// Note that the journal is stored unencrypted, like the stored conversations.
+type Journal struct {
+	path string
+	// saved is the session state recorded in the journal.
+	saved *Session
+}
+
+// NewJournal returns a new autosave journal. The journal file is created
+// once the first turn is recorded. The journals of the older sessions
+// are removed, except for the most recent ones.
+func (s *Store) NewJournal() (*Journal, error) {
+	id, err := newID(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.pruneJournals(maxJournals - 1); err != nil {
+		return nil, err
+	}
+
+	return &Journal{path: filepath.Join(s.dir, journalDir, id+journalExtension)}, nil
+}
+
+// LatestJournal returns the most recently updated autosave journal along with
+// the session state recorded in it. The turns recorded afterward are appended
+// to the journal. If there are no journals, an error wrapping ErrNoJournal
+// is returned.
+func (s *Store) LatestJournal() (*Journal, *Session, error) {
+	paths, err := s.journalPaths()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(paths) == 0 {
+		return nil, nil, ErrNoJournal
+	}
+
+	journal := &Journal{path: paths[len(paths)-1]}
+	session, err := journal.replay()
+	if err != nil {
+		return nil, nil, err
+	}
+	journal.saved = session.clone()
+
+	return journal, session, nil
+}
+
+// Record appends the changes of the session state since the last record to
+// the journal. If the history was replaced, or the settings of the session
+// changed, the whole state is recorded. Nothing is recorded before the first
+// message.
+func (j *Journal) Record(session *Session) error {
+	record := &journalRecord{Time: time.Now()}
+	switch {
+	case j.saved == nil && len(session.History) == 0:
+		return nil
+	case j.saved != nil && session.extends(j.saved):
+		if len(session.History) == len(j.saved.History) {
+			return nil // unchanged
+		}
+		record.Contents = serializeContents(session.History[len(j.saved.History):])
+	default:
+		record.Reset = true
+		record.Model = session.Model
+		record.SystemPrompt = session.SystemPrompt
+		record.SystemInstruction = serializeContent(session.SystemInstruction)
+		record.GenerationConfig = session.GenerationConfig
+		record.Contents = serializeContents(session.History)
+	}
+
+	if err := j.append(record); err != nil {
+		return fmt.Errorf("error writing autosave journal: %w", err)
+	}
+	j.saved = session.clone()
+
+	return nil
+}
+
+// append writes the record to the end of the journal file.
+func (j *Journal) append(record *journalRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(j.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// replay returns the session state recorded in the journal. An incomplete
+// last record, left by an interrupted write, is discarded and truncated,
+// so that the following records can be appended.
+func (j *Journal) replay() (*Session, error) {
+	file, err := os.OpenFile(j.path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error opening autosave journal: %w", err)
+	}
+	defer file.Close()
+
+	session := &Session{}
+	reader := bufio.NewReader(file)
+	var size int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				if err := file.Truncate(size); err != nil {
+					return nil, fmt.Errorf("error truncating autosave journal: %w", err)
+				}
+			}
+			return session, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading autosave journal: %w", err)
+		}
+		size += int64(len(line))
+
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		record := &journalRecord{}
+		if err := json.Unmarshal(line, record); err != nil {
+			return nil, fmt.Errorf("error decoding autosave journal %s: %w", j.path, err)
+		}
+		if record.Reset {
+			session = &Session{
+				Model:             record.Model,
+				SystemPrompt:      record.SystemPrompt,
+				SystemInstruction: deserializeContent(record.SystemInstruction),
+				GenerationConfig:  record.GenerationConfig,
+			}
+		}
+		session.History = append(session.History, deserializeContents(record.Contents)...)
+	}
+}
+
+// journalPaths returns the paths to the autosave journal files, ordered by
+// the modification time.
+func (s *Store) journalPaths() ([]string, error) {
+	dirEntries, err := os.ReadDir(filepath.Join(s.dir, journalDir))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading autosave directory: %w", err)
+	}
+
+	type journalFile struct {
+		path    string
+		modTime time.Time
+	}
+	files := make([]journalFile, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), journalExtension) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue // removed concurrently
+		}
+		files = append(files, journalFile{
+			path:    filepath.Join(s.dir, journalDir, dirEntry.Name()),
+			modTime: info.ModTime(),
+		})
+	}
+	slices.SortStableFunc(files, func(a, b journalFile) int {
+		return a.modTime.Compare(b.modTime)
+	})
+
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.path
+	}
+	return paths, nil
+}
+
+// pruneJournals removes the autosave journals, except for the given number
+// of the most recently updated ones.
+func (s *Store) pruneJournals(keep int) error {
+	paths, err := s.journalPaths()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, path := range paths[:max(len(paths)-keep, 0)] {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("error removing autosave journal: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,190 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestJournalRecord(t *testing.T) {
+	store := NewStore(t.TempDir())
+	journal, err := store.NewJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := &Session{Model: "model-a"}
+	if err := journal.Record(session); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(journal.path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("journal created before the first message: %v", err)
+	}
+
+	session.History = newHistory("q1", "a1")
+	recordSession(t, journal, session)
+	session.History = append(session.History, newHistory("q2", "a2")...)
+	recordSession(t, journal, session)
+	// unchanged
+	recordSession(t, journal, session)
+
+	session = session.clone()
+	session.Model = "model-b"
+	recordSession(t, journal, session)
+
+	session = session.clone()
+	session.History = newHistory("q3", "a3")
+	recordSession(t, journal, session)
+
+	records := readRecords(t, journal.path)
+	resets := make([]bool, len(records))
+	for i, record := range records {
+		resets[i] = record.Reset
+	}
+	if want := []bool{true, false, true, true}; !slices.Equal(resets, want) {
+		t.Errorf("reset records = %v, want %v", resets, want)
+	}
+
+	_, restored, err := store.LatestJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Model != "model-b" {
+		t.Errorf("model = %q, want %q", restored.Model, "model-b")
+	}
+	assertHistory(t, restored.History, "q3", "a3")
+}
+
+func TestJournalTruncatedRecord(t *testing.T) {
+	store := NewStore(t.TempDir())
+	journal, err := store.NewJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordSession(t, journal, &Session{Model: "model", History: newHistory("q1", "a1")})
+
+	info, err := os.Stat(journal.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// simulate a write interrupted in the middle of a record
+	file, err := os.OpenFile(journal.path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString(`{"time":"2025-01-01T00:00:00Z","contents":[{"Ver`); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	restoredJournal, session, err := store.LatestJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertHistory(t, session.History, "q1", "a1")
+	truncated, err := os.Stat(journal.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if truncated.Size() != info.Size() {
+		t.Errorf("journal size = %d, want %d", truncated.Size(), info.Size())
+	}
+
+	// the following turns are appended to the restored journal
+	session.History = append(session.History, newHistory("q2", "a2")...)
+	recordSession(t, restoredJournal, session)
+
+	records := readRecords(t, journal.path)
+	if len(records) != 2 || records[1].Reset {
+		t.Errorf("expected an appended record, got %d records", len(records))
+	}
+	_, session, err = store.LatestJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertHistory(t, session.History, "q1", "a1", "q2", "a2")
+}
+
+func TestJournalInvalidRecord(t *testing.T) {
+	store := NewStore(t.TempDir())
+	journal, err := store.NewJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordSession(t, journal, &Session{History: newHistory("q1", "a1")})
+
+	data, err := os.ReadFile(journal.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(journal.path, append([]byte("{invalid\n"), data...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := store.LatestJournal(); err == nil {
+		t.Error("expected error decoding the complete invalid record")
+	}
+}
+
+func TestLatestJournalEmpty(t *testing.T) {
+	store := NewStore(t.TempDir())
+	if _, _, err := store.LatestJournal(); !errors.Is(err, ErrNoJournal) {
+		t.Errorf("expected ErrNoJournal, got %v", err)
+	}
+}
+
+// newHistory returns the history of the alternating user and model messages.
+func newHistory(texts ...string) []*genai.Content {
+	history := make([]*genai.Content, len(texts))
+	for i, text := range texts {
+		role := genai.Role(genai.RoleUser)
+		if i%2 == 1 {
+			role = genai.RoleModel
+		}
+		history[i] = genai.NewContentFromText(text, role)
+	}
+	return history
+}
+
+func recordSession(t *testing.T, journal *Journal, session *Session) {
+	t.Helper()
+	if err := journal.Record(session); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readRecords(t *testing.T, path string) []*journalRecord {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var records []*journalRecord
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		record := &journalRecord{}
+		if err := json.Unmarshal(line, record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func assertHistory(t *testing.T, history []*genai.Content, texts ...string) {
+	t.Helper()
+	actual := make([]string, len(history))
+	for i, content := range history {
+		actual[i] = content.Parts[0].Text
+	}
+	if !slices.Equal(actual, texts) {
+		t.Errorf("history = %q, want %q", actual, texts)
+	}
+}
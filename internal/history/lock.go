@@ -0,0 +1,53 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// lockTimeout is the time to wait for the index lock held by another
+// application instance.
+var lockTimeout = 5 * time.Second
+
+const (
+	lockFileName = "index.lock"
+	// staleLockAge is the age of the lock file, after which it is considered
+	// to be left by an instance that exited without releasing it.
+	staleLockAge      = 30 * time.Second
+	lockRetryInterval = 20 * time.Millisecond
+)
+
+// lockIndex acquires the lock of the index, so that the concurrent updates
+// of the index by several application instances are not lost. It returns
+// the function releasing the lock.
+// The lock is a file created exclusively in the store directory. If the
+// directory does not exist, nothing is stored, and no lock is taken.
+func (s *Store) lockIndex() (func(), error) {
+	path := filepath.Join(s.dir, lockFileName)
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		switch {
+		case err == nil:
+			file.Close()
+			return func() { _ = os.Remove(path) }, nil
+		case errors.Is(err, fs.ErrNotExist):
+			return func() {}, nil
+		case !errors.Is(err, fs.ErrExist):
+			return nil, fmt.Errorf("error locking history index: %w", err)
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			_ = os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("error locking history index: %s is held by another instance", path)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
@@ -0,0 +1,103 @@
+package history
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockIndex(t *testing.T) {
+	store := NewStore(t.TempDir())
+	lockPath := filepath.Join(store.Dir(), lockFileName)
+
+	unlock, err := store.lockIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Fatalf("expected the lock file: %v", err)
+	}
+
+	// the lock is acquired once released by the holder
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(5 * lockRetryInterval)
+		close(released)
+		unlock()
+	}()
+	unlock, err = store.lockIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-released:
+	default:
+		t.Error("the lock was acquired while held")
+	}
+
+	unlock()
+	if _, err := os.Stat(lockPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the lock file to be removed: %v", err)
+	}
+}
+
+func TestLockIndexStale(t *testing.T) {
+	store := NewStore(t.TempDir())
+	lockPath := filepath.Join(store.Dir(), lockFileName)
+	if err := os.WriteFile(lockPath, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Now().Add(-2 * staleLockAge)
+	if err := os.Chtimes(lockPath, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	unlock, err := store.lockIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	if elapsed := time.Since(start); elapsed >= lockTimeout {
+		t.Errorf("the stale lock was taken over after %s", elapsed)
+	}
+}
+
+func TestLockIndexTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { lockTimeout = timeout }(lockTimeout)
+	lockTimeout = 10 * lockRetryInterval
+
+	store := NewStore(t.TempDir())
+	unlock, err := store.lockIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	start := time.Now()
+	if _, err := store.lockIndex(); err == nil {
+		t.Fatal("expected the lock timeout error")
+	}
+	if elapsed := time.Since(start); elapsed < lockTimeout {
+		t.Errorf("the lock timed out after %s, want at least %s", elapsed, lockTimeout)
+	}
+
+	// the store is not updated while the lock is held by another instance
+	if err := store.Save(NewConversation("label", &Session{})); err == nil {
+		t.Error("expected the save to fail while the lock is held")
+	}
+}
+
+func TestLockIndexMissingDir(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "missing"))
+	unlock, err := store.lockIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	if _, err := os.Stat(store.Dir()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the store directory not to be created: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package history
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/reugn/gemini-cli/gemini"
+)
+
+// legacyLabelSeparator separates the time prefix of the legacy record labels
+// from the user-defined label.
+const legacyLabelSeparator = " - "
+
+// Import stores the history records kept in the configuration file by the
+// earlier versions of the application, keyed by label. The creation time is
+// parsed from the label prefix, or set to the current time if there is none.
+// The records already imported are skipped, so that an interrupted migration
+// can be repeated.
+func (s *Store) Import(records map[string][]*gemini.SerializableContent) error {
+	entries, err := s.List()
+	if err != nil {
+		return err
+	}
+
+	labels := make([]string, 0, len(records))
+	for label := range records {
+		labels = append(labels, label)
+	}
+	slices.Sort(labels) // the time-prefixed labels are imported chronologically
+
+	for _, recordLabel := range labels {
+		label, created := parseLegacyLabel(recordLabel)
+		if slices.ContainsFunc(entries, func(e *Entry) bool {
+			return e.Label == label && (created.IsZero() || e.Created.Equal(created))
+		}) {
+			continue
+		}
+		if created.IsZero() {
+			created = time.Now()
+		}
+
+		conversation := NewConversation(label, &Session{
+			History: deserializeContents(records[recordLabel]),
+		})
+		conversation.Created = created
+		conversation.Updated = created
+		if err := s.save(conversation); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseLegacyLabel returns the user-defined label and the creation time of
+// the legacy record label. If the label has no time prefix, the zero time
+// is returned.
+func parseLegacyLabel(recordLabel string) (string, time.Time) {
+	prefix, label, ok := strings.Cut(recordLabel, legacyLabelSeparator)
+	if !ok {
+		return recordLabel, time.Time{}
+	}
+
+	created, err := time.ParseInLocation(time.DateTime, prefix, time.Local)
+	if err != nil {
+		return recordLabel, time.Time{}
+	}
+	return label, created
+}
@@ -0,0 +1,88 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/reugn/gemini-cli/gemini"
+	"google.golang.org/genai"
+)
+
+func TestImport(t *testing.T) {
+	store := NewStore(t.TempDir())
+	records := map[string][]*gemini.SerializableContent{
+		"2024-05-02 10:00:00 - second": serializeContents(newHistory("q2", "a2")),
+		"2024-05-01 09:30:00 - first":  serializeContents(newHistory("q1", "a1", "q1b", "a1b")),
+		"unprefixed":                   serializeContents(newHistory("q3", "a3")),
+		"not a time - label":           serializeContents(newHistory("q4", "a4")),
+	}
+	start := time.Now()
+	if err := store.Import(records); err != nil {
+		t.Fatal(err)
+	}
+	// the imported records are not repeated
+	if err := store.Import(records); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byLabel := make(map[string]*Entry, len(entries))
+	for _, entry := range entries {
+		byLabel[entry.Label] = entry
+	}
+	if len(entries) != 4 || len(byLabel) != 4 {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+
+	first := byLabel["first"]
+	if first == nil {
+		t.Fatal("expected the time prefix to be removed from the label")
+	}
+	created := time.Date(2024, 5, 1, 9, 30, 0, 0, time.Local)
+	if !first.Created.Equal(created) || !first.Updated.Equal(created) || first.Turns != 2 {
+		t.Errorf("unexpected entry %+v", first)
+	}
+	conversation, err := store.Load(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertHistory(t, conversation.Session().History, "q1", "a1", "q1b", "a1b")
+
+	for _, label := range []string{"unprefixed", "not a time - label"} {
+		entry := byLabel[label]
+		if entry == nil || entry.Created.Before(start) {
+			t.Errorf("expected %q to be created at the import time, got %+v", label, entry)
+		}
+	}
+	if entries[len(entries)-1].Label != "first" {
+		t.Errorf("expected the oldest record last, got %q", entries[len(entries)-1].Label)
+	}
+}
+
+func TestImportLostContent(t *testing.T) {
+	store := NewStore(t.TempDir())
+	// a legacy record, where the non-text parts were saved as empty strings
+	legacy := &gemini.SerializableContent{}
+	if err := legacy.UnmarshalJSON([]byte(`{"Parts": [""], "Role": "user"}`)); err != nil {
+		t.Fatal(err)
+	}
+	records := map[string][]*gemini.SerializableContent{
+		"image": {legacy, gemini.NewSerializableContent(genai.NewContentFromText("a", genai.RoleModel))},
+	}
+	if err := store.Import(records); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conversation, err := store.Load(entries[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertHistory(t, conversation.Session().History, "[non-text content lost]", "a")
+}
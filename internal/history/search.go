@@ -0,0 +1,252 @@
+package history
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/reugn/gemini-cli/gemini"
+)
+
+const (
+	// snippetContext is the number of bytes of the text shown around the
+	// first matched term of a snippet.
+	snippetContext = 60
+	// maxSnippets is the number of the snippets returned per conversation.
+	maxSnippets = 3
+	// labelMatchScore is the score of a term matched in the conversation
+	// label, which outweighs the matches in the messages.
+	labelMatchScore = 10
+)
+
+// Match is a stored conversation matching the search query.
+type Match struct {
+	*Entry
+	// Score is the relevance of the conversation; the higher the better.
+	Score int
+	// Snippets contains the excerpts of the best matching messages.
+	Snippets []*Snippet
+}
+
+// Snippet is an excerpt of a message matching the search query.
+type Snippet struct {
+	// Role is the role of the message author.
+	Role string
+	// Text is the excerpt of the message text.
+	Text string
+	// Ranges contains the start and end offsets of the matched terms in
+	// the text.
+	Ranges [][2]int
+}
+
+// Highlight returns the text of the snippet with the matched terms wrapped
+// using the highlight function.
+func (s *Snippet) Highlight(highlight func(string) string) string {
+	var builder strings.Builder
+	var offset int
+	for _, r := range s.Ranges {
+		builder.WriteString(s.Text[offset:r[0]])
+		builder.WriteString(highlight(s.Text[r[0]:r[1]]))
+		offset = r[1]
+	}
+	builder.WriteString(s.Text[offset:])
+	return builder.String()
+}
+
+// messageMatch is a message containing the search terms.
+type messageMatch struct {
+	role string
+	text string
+	// the number of the distinct terms found in the message
+	terms int
+	// the number of the term occurrences in the message
+	hits int
+}
+
+// Search returns the stored conversations containing all of the query terms,
+// in the labels or in the text parts of the messages, ignoring case. The
+// matches are ranked by relevance, and then by the update time.
+func (s *Store) Search(query string) ([]*Match, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*Match
+	for _, entry := range entries {
+		conversation, err := s.Load(entry.ID)
+		if err != nil {
+			return nil, err
+		}
+		if match := matchConversation(entry, conversation, terms); match != nil {
+			matches = append(matches, match)
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b *Match) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), b.Updated.Compare(a.Updated))
+	})
+
+	return matches, nil
+}
+
+// matchConversation returns the match of the conversation with the index
+// entry, or nil if some of the terms are not found.
+func matchConversation(entry *Entry, conversation *Conversation, terms []string) *Match {
+	found := make([]bool, len(terms))
+	match := &Match{Entry: entry}
+
+	label := strings.ToLower(entry.Label)
+	for i, term := range terms {
+		if strings.Contains(label, term) {
+			found[i] = true
+			match.Score += labelMatchScore
+		}
+	}
+
+	var messages []*messageMatch
+	for _, content := range conversation.History {
+		text := contentText(content)
+		lower := strings.ToLower(text)
+		message := &messageMatch{role: content.Role, text: text}
+		for i, term := range terms {
+			if count := strings.Count(lower, term); count > 0 {
+				found[i] = true
+				message.terms++
+				message.hits += count
+			}
+		}
+		if message.hits > 0 {
+			messages = append(messages, message)
+			// the messages containing more of the terms are more relevant
+			match.Score += message.hits + message.terms*message.terms
+		}
+	}
+	if slices.Contains(found, false) {
+		return nil
+	}
+
+	slices.SortStableFunc(messages, func(a, b *messageMatch) int {
+		return cmp.Or(cmp.Compare(b.terms, a.terms), cmp.Compare(b.hits, a.hits))
+	})
+	for _, message := range messages[:min(len(messages), maxSnippets)] {
+		match.Snippets = append(match.Snippets, newSnippet(message, terms))
+	}
+
+	return match
+}
+
+// newSnippet returns the excerpt of the message around the first occurrence
+// of the terms, along with the ranges of the terms in the excerpt.
+func newSnippet(message *messageMatch, terms []string) *Snippet {
+	text := strings.Join(strings.Fields(message.text), " ")
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		// the case mapping changed the byte offsets; show the lowercase text
+		text = lower
+	}
+
+	first := len(lower)
+	for _, term := range terms {
+		if i := strings.Index(lower, term); i >= 0 {
+			first = min(first, i)
+		}
+	}
+	start := runeStart(text, max(first-snippetContext, 0))
+	end := runeStart(text, min(first+2*snippetContext, len(text)))
+	// cut the excerpt at the word boundaries
+	if i := strings.IndexByte(text[start:first], ' '); start > 0 && i >= 0 {
+		start += i + 1
+	}
+	if i := strings.LastIndexByte(text[first:end], ' '); end < len(text) && i > 0 {
+		end = first + i
+	}
+
+	snippet := &Snippet{Role: message.role, Text: text[start:end]}
+	snippet.Ranges = termRanges(lower[start:end], terms)
+	if start > 0 {
+		snippet.Text = "…" + snippet.Text
+		shiftRanges(snippet.Ranges, len("…"))
+	}
+	if end < len(text) {
+		snippet.Text += "…"
+	}
+
+	return snippet
+}
+
+// termRanges returns the sorted non-overlapping ranges of the terms in the
+// lowercase text.
+func termRanges(lower string, terms []string) [][2]int {
+	var ranges [][2]int
+	for _, term := range terms {
+		for offset := 0; ; {
+			i := strings.Index(lower[offset:], term)
+			if i < 0 {
+				break
+			}
+			ranges = append(ranges, [2]int{offset + i, offset + i + len(term)})
+			offset += i + len(term)
+		}
+	}
+	slices.SortFunc(ranges, func(a, b [2]int) int {
+		return cmp.Compare(a[0], b[0])
+	})
+
+	merged := ranges[:0]
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && r[0] <= merged[n-1][1] {
+			merged[n-1][1] = max(merged[n-1][1], r[1])
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// shiftRanges moves the ranges by the offset.
+func shiftRanges(ranges [][2]int, offset int) {
+	for i := range ranges {
+		ranges[i][0] += offset
+		ranges[i][1] += offset
+	}
+}
+
+// runeStart returns the offset of the start of the rune containing the
+// byte at the offset.
+func runeStart(text string, offset int) int {
+	for offset > 0 && offset < len(text) && !utf8.RuneStart(text[offset]) {
+		offset--
+	}
+	return offset
+}
+
+// searchTerms returns the distinct lowercase terms of the query.
+func searchTerms(query string) []string {
+	var terms []string
+	for _, term := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return unicode.IsSpace(r) || r == '"'
+	}) {
+		if !slices.Contains(terms, term) {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// contentText returns the text parts of the content joined by newlines.
+func contentText(content *gemini.SerializableContent) string {
+	var texts []string
+	for _, part := range content.Parts {
+		if part != nil && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
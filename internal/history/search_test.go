@@ -0,0 +1,122 @@
+package history
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearch(t *testing.T) {
+	store := NewStore(t.TempDir())
+	updated := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		label    string
+		messages []string
+	}{
+		{"gardening", []string{"How to grow TOMATOES?", "Tomatoes need sun."}},
+		{"recipes", []string{"A tomato soup recipe", "Use ripe tomatoes and basil."}},
+		{"travel", []string{"Trip to Rome", "Visit the Colosseum."}},
+		{"tomato sauce", []string{"How long to cook it?", "About an hour."}},
+		{"old recipes", []string{"A tomato soup recipe", "Use ripe tomatoes and basil."}},
+	} {
+		conversation := NewConversation(c.label, &Session{History: newHistory(c.messages...)})
+		// the later conversations are updated more recently
+		updated = updated.Add(time.Hour)
+		conversation.Created, conversation.Updated = updated, updated
+		if err := store.save(conversation); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		labels []string
+	}{
+		{"empty", "  ", nil},
+		{"no match", "pasta", nil},
+		{"case insensitive", "tomatoes", []string{"gardening", "old recipes", "recipes"}},
+		{"label match first", "tomato", []string{"tomato sauce", "old recipes", "recipes", "gardening"}},
+		{"all terms", "tomato BASIL", []string{"old recipes", "recipes"}},
+		{"quoted terms", `"rome" colosseum`, []string{"travel"}},
+		{"label and message terms", "sauce hour", []string{"tomato sauce"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := store.Search(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			labels := make([]string, len(matches))
+			for i, match := range matches {
+				labels[i] = match.Label
+			}
+			if !slices.Equal(labels, tt.labels) {
+				t.Errorf("labels = %q, want %q", labels, tt.labels)
+			}
+		})
+	}
+}
+
+func TestSearchSnippets(t *testing.T) {
+	store := NewStore(t.TempDir())
+	history := newHistory(
+		"first mention of go",
+		"Go and Rust and GO again",
+		strings.Repeat("word ", 30)+"rust is here "+strings.Repeat("word ", 30),
+		"no match",
+		"rust",
+	)
+	if err := store.Save(NewConversation("languages", &Session{History: history})); err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := store.Search("rust go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("matches = %d, want 1", len(matches))
+	}
+
+	highlight := func(s string) string { return "[" + s + "]" }
+	var snippets []string
+	for _, snippet := range matches[0].Snippets {
+		snippets = append(snippets, snippet.Highlight(highlight))
+	}
+	// the messages containing more of the terms come first
+	want := []string{
+		"[Go] and [Rust] and [GO] again",
+		"first mention of [go]",
+		// the excerpt around the term is cut at the word boundaries
+		"…" + strings.Repeat("word ", 11) + "[rust] is here" + strings.Repeat(" word", 21) + "…",
+	}
+	if !slices.Equal(snippets, want) {
+		t.Errorf("snippets = %q, want %q", snippets, want)
+	}
+}
+
+func TestSnippetCaseMapping(t *testing.T) {
+	// the lowercase of "İ" is longer in bytes, which changes the offsets
+	message := &messageMatch{role: "user", text: "İstanbul  and\nAnkara"}
+	snippet := newSnippet(message, []string{"ankara"})
+	want := strings.ToLower("İstanbul") + " and ANKARA"
+	if text := snippet.Highlight(strings.ToUpper); text != want {
+		t.Errorf("snippet = %q, want %q", text, want)
+	}
+}
+
+func TestTermRanges(t *testing.T) {
+	ranges := termRanges("abcabc bc", []string{"bc", "abc", "c"})
+	if want := [][2]int{{0, 6}, {7, 9}}; !slices.Equal(ranges, want) {
+		t.Errorf("ranges = %v, want %v", ranges, want)
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	terms := searchTerms(` Go "go"  Rust	GO rust's `)
+	if want := []string{"go", "rust", "rust's"}; !slices.Equal(terms, want) {
+		t.Errorf("terms = %q, want %q", terms, want)
+	}
+}
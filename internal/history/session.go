@@ -0,0 +1,116 @@
+package history
+
+import (
+	"reflect"
+	"slices"
+
+	"github.com/reugn/gemini-cli/gemini"
+	"google.golang.org/genai"
+)
+
+// Session is the state of a chat session kept in the history, which is
+// restored to continue the conversation.
+type Session struct {
+	// Model is the name of the generative model used in the session.
+	Model string
+	// SystemPrompt is the label of the configured system prompt active in
+	// the session, or an empty string if there is none.
+	SystemPrompt string
+	// SystemInstruction is the system instruction active in the session.
+	SystemInstruction *genai.Content
+	// GenerationConfig contains the generation parameters of the session.
+	GenerationConfig *gemini.GenerationConfig
+	// History contains the messages of the session.
+	History []*genai.Content
+}
+
+// NewSession returns the current state of the chat session, with the label
+// of the active system prompt.
+func NewSession(chatSession *gemini.ChatSession, systemPrompt string) *Session {
+	return &Session{
+		Model:             chatSession.Model(),
+		SystemPrompt:      systemPrompt,
+		SystemInstruction: chatSession.SystemInstruction(),
+		GenerationConfig:  chatSession.GenerationConfig(),
+		History:           chatSession.GetHistory(),
+	}
+}
+
+// Restore sets the model, the system instruction, the generation parameters
+// and the history of the chat session. The settings not recorded in the
+// session are left unchanged.
+func (s *Session) Restore(chatSession *gemini.ChatSession) error {
+	if s.Model != "" {
+		if err := chatSession.SetModel(s.Model); err != nil {
+			return err
+		}
+	}
+	if err := chatSession.SetSystemInstruction(s.SystemInstruction); err != nil {
+		return err
+	}
+	if s.GenerationConfig != nil {
+		if err := chatSession.SetGenerationConfig(s.GenerationConfig); err != nil {
+			return err
+		}
+	}
+	return chatSession.SetHistory(s.History)
+}
+
+// clone returns a copy of the session, sharing the messages.
+func (s *Session) clone() *Session {
+	return &Session{
+		Model:             s.Model,
+		SystemPrompt:      s.SystemPrompt,
+		SystemInstruction: s.SystemInstruction,
+		GenerationConfig:  s.GenerationConfig,
+		History:           slices.Clone(s.History),
+	}
+}
+
+// extends returns true if the session continues the other one, i.e. it has
+// the same model, system prompt and generation parameters, and the history
+// of the other session is a prefix of its history.
+func (s *Session) extends(other *Session) bool {
+	return s.Model == other.Model &&
+		s.SystemPrompt == other.SystemPrompt &&
+		s.SystemInstruction == other.SystemInstruction &&
+		reflect.DeepEqual(s.GenerationConfig, other.GenerationConfig) &&
+		len(s.History) >= len(other.History) &&
+		slices.Equal(s.History[:len(other.History)], other.History)
+}
+
+// serializeContents returns the serializable representation of the contents.
+func serializeContents(contents []*genai.Content) []*gemini.SerializableContent {
+	serializable := make([]*gemini.SerializableContent, len(contents))
+	for i, content := range contents {
+		serializable[i] = gemini.NewSerializableContent(content)
+	}
+	return serializable
+}
+
+// serializeContent returns the serializable representation of the content,
+// or nil if the content is nil.
+func serializeContent(content *genai.Content) *gemini.SerializableContent {
+	if content == nil {
+		return nil
+	}
+	return gemini.NewSerializableContent(content)
+}
+
+// deserializeContent returns the content of the serializable representation,
+// or nil if it is nil.
+func deserializeContent(serializable *gemini.SerializableContent) *genai.Content {
+	if serializable == nil {
+		return nil
+	}
+	return serializable.ToContent()
+}
+
+// deserializeContents returns the contents of the serializable representation.
+func deserializeContents(serializable []*gemini.SerializableContent) []*genai.Content {
+	contents := make([]*genai.Content, len(serializable))
+	for i, content := range serializable {
+		contents[i] = content.ToContent()
+	}
+	return contents
+}
@@ -0,0 +1,377 @@
+// Package history implements the chat history store, which keeps each stored
+// conversation in a separate file, along with an index of the conversations.
+package history
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/reugn/gemini-cli/gemini"
+	"google.golang.org/genai"
+)
+
+const (
+	indexFileName = "index.json"
+	fileExtension = ".json"
+)
+
+// ErrNotFound is returned when the requested conversation is not stored.
+var ErrNotFound = errors.New("conversation not found")
+
+// Entry is the index entry describing a stored conversation.
+type Entry struct {
+	// ID is the unique identifier of the conversation, which is also the name
+	// of the conversation file.
+	ID string `json:"id"`
+	// Label is the user-defined label of the conversation.
+	Label string `json:"label"`
+	// Model is the name of the generative model used in the conversation.
+	Model string `json:"model,omitempty"`
+	// SystemPrompt is the label of the configured system prompt active in
+	// the conversation.
+	SystemPrompt string `json:"system_prompt,omitempty"`
+	// Created is the time the conversation was first stored.
+	Created time.Time `json:"created"`
+	// Updated is the time the conversation was last stored.
+	Updated time.Time `json:"updated"`
+	// Turns is the number of the user turns in the conversation.
+	Turns int `json:"turns"`
+	// Tokens is the size of the conversation context in tokens, including
+	// the system instruction. Zero if the size is unknown.
+	Tokens int32 `json:"tokens,omitempty"`
+}
+
+// Conversation is a stored chat conversation.
+// Note that the history is stored unencrypted, with binary data base64-encoded.
+type Conversation struct {
+	Entry
+	// SystemInstruction is the system instruction active in the conversation.
+	SystemInstruction *gemini.SerializableContent `json:"system_instruction,omitempty"`
+	// GenerationConfig contains the generation parameters of the conversation.
+	GenerationConfig *gemini.GenerationConfig `json:"generation_config,omitempty"`
+	// History contains the messages of the conversation.
+	History []*gemini.SerializableContent `json:"history"`
+}
+
+// NewConversation returns a new Conversation with the state of the chat session.
+func NewConversation(label string, session *Session) *Conversation {
+	conversation := &Conversation{
+		Entry: Entry{
+			Label: label,
+		},
+	}
+	conversation.SetSession(session)
+
+	return conversation
+}
+
+// SetSession replaces the settings and the messages of the conversation.
+// The token count is reset, as it is unknown for the new messages.
+func (c *Conversation) SetSession(session *Session) {
+	c.Model = session.Model
+	c.SystemPrompt = session.SystemPrompt
+	c.SystemInstruction = serializeContent(session.SystemInstruction)
+	c.GenerationConfig = session.GenerationConfig
+	c.Tokens = 0
+	c.History = serializeContents(session.History)
+	c.Turns = countTurns(session.History)
+}
+
+// Session returns the state of the chat session of the conversation.
+func (c *Conversation) Session() *Session {
+	return &Session{
+		Model:             c.Model,
+		SystemPrompt:      c.SystemPrompt,
+		SystemInstruction: deserializeContent(c.SystemInstruction),
+		GenerationConfig:  c.GenerationConfig,
+		History:           deserializeContents(c.History),
+	}
+}
+
+// Store is the chat history store located in a directory. The directory
+// is created once the first conversation is saved.
+type Store struct {
+	dir string
+}
+
+// NewStore returns a new Store located in the directory.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the directory of the store.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// List returns the index entries of the stored conversations, the most
+// recently updated first.
+func (s *Store) List() ([]*Entry, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, indexFileName))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading history index: %w", err)
+	}
+
+	var entries []*Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("error decoding history index: %w", err)
+	}
+	slices.SortStableFunc(entries, func(a, b *Entry) int {
+		return b.Updated.Compare(a.Updated)
+	})
+
+	return entries, nil
+}
+
+// Load returns the stored conversation with the ID.
+func (s *Store) Load(id string) (*Conversation, error) {
+	path, err := s.conversationPath(id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("error reading conversation: %w", err)
+	}
+
+	conversation := &Conversation{}
+	if err := json.Unmarshal(data, conversation); err != nil {
+		return nil, fmt.Errorf("error decoding conversation %s: %w", id, err)
+	}
+
+	return conversation, nil
+}
+
+// Save stores the conversation and updates the index. A new conversation is
+// assigned an ID. The update time is set to the current time.
+func (s *Store) Save(conversation *Conversation) error {
+	now := time.Now()
+	if conversation.Created.IsZero() {
+		conversation.Created = now
+	}
+	conversation.Updated = now
+
+	return s.save(conversation)
+}
+
+// save stores the conversation preserving its timestamps, and updates the index.
+func (s *Store) save(conversation *Conversation) error {
+	if conversation.ID == "" {
+		id, err := newID(conversation.Created)
+		if err != nil {
+			return err
+		}
+		conversation.ID = id
+	}
+
+	path, err := s.conversationPath(conversation.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return fmt.Errorf("error creating history directory: %w", err)
+	}
+	if err := writeJSON(path, conversation); err != nil {
+		return err
+	}
+
+	unlock, err := s.lockIndex()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := s.List()
+	if err != nil {
+		return err
+	}
+	entry := conversation.Entry
+	if i := slices.IndexFunc(entries, func(e *Entry) bool { return e.ID == entry.ID }); i >= 0 {
+		entries[i] = &entry
+	} else {
+		entries = append(entries, &entry)
+	}
+
+	return s.writeIndex(entries)
+}
+
+// Rename changes the label of the stored conversation with the ID.
+// The update time is preserved.
+func (s *Store) Rename(id, label string) error {
+	conversation, err := s.Load(id)
+	if err != nil {
+		return err
+	}
+
+	conversation.Label = label
+	return s.save(conversation)
+}
+
+// Duplicate stores a copy of the conversation with the ID under the label,
+// and returns the copy.
+func (s *Store) Duplicate(id, label string) (*Conversation, error) {
+	conversation, err := s.Load(id)
+	if err != nil {
+		return nil, err
+	}
+
+	conversation.ID = ""
+	conversation.Label = label
+	conversation.Created = time.Time{}
+	if err := s.Save(conversation); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// Delete removes the stored conversation with the ID.
+func (s *Store) Delete(id string) error {
+	path, err := s.conversationPath(id)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := s.lockIndex()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := s.List()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(entries, func(e *Entry) bool { return e.ID == id })
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	if err := s.writeIndex(slices.Delete(entries, i, i+1)); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error removing conversation: %w", err)
+	}
+
+	return nil
+}
+
+// Clear removes all of the stored conversations.
+func (s *Store) Clear() error {
+	unlock, err := s.lockIndex()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := s.List()
+	if err != nil {
+		return err
+	}
+
+	if err := s.writeIndex(nil); err != nil {
+		return err
+	}
+	var errs []error
+	for _, entry := range entries {
+		path, err := s.conversationPath(entry.ID)
+		if err == nil {
+			err = os.Remove(path)
+		}
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("error removing conversation: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// writeIndex writes the index entries, if the store directory exists.
+func (s *Store) writeIndex(entries []*Entry) error {
+	if entries == nil {
+		entries = []*Entry{}
+	}
+	err := writeJSON(filepath.Join(s.dir, indexFileName), entries)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil // nothing is stored yet
+	}
+	return err
+}
+
+// conversationPath returns the path to the file of the conversation.
+func (s *Store) conversationPath(id string) (string, error) {
+	if id == "" || id != filepath.Base(id) || id == "." || id == ".." {
+		return "", fmt.Errorf("invalid conversation id: %q", id)
+	}
+	return filepath.Join(s.dir, id+fileExtension), nil
+}
+
+// writeJSON writes the value encoded in JSON to the file. The file is replaced
+// atomically, so that it is not corrupted if the write fails.
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding JSON: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	defer os.Remove(file.Name()) // no-op once renamed
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing file: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("error syncing file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error closing file: %w", err)
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("error replacing file: %w", err)
+	}
+	return nil
+}
+
+// newID returns a new unique conversation ID, starting with the creation time.
+func newID(created time.Time) (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("error generating conversation id: %w", err)
+	}
+	return created.UTC().Format("20060102-150405") + "-" + hex.EncodeToString(suffix), nil
+}
+
+// countTurns returns the number of the user turns in the history, excluding
+// the function responses.
+func countTurns(history []*genai.Content) int {
+	var turns int
+	for _, content := range history {
+		if content.Role == genai.RoleUser && slices.ContainsFunc(content.Parts, func(part *genai.Part) bool {
+			return part.FunctionResponse == nil
+		}) {
+			turns++
+		}
+	}
+	return turns
+}
@@ -0,0 +1,180 @@
+package history
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/reugn/gemini-cli/gemini"
+	"google.golang.org/genai"
+)
+
+func TestStoreSaveLoad(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "history"))
+	if entries, err := store.List(); err != nil || entries != nil {
+		t.Fatalf("expected no entries before the first save, got %v, %v", entries, err)
+	}
+
+	temperature := float32(0.5)
+	session := &Session{
+		Model:             "model",
+		SystemPrompt:      "brief",
+		SystemInstruction: genai.NewContentFromText("be brief", genai.RoleUser),
+		GenerationConfig:  &gemini.GenerationConfig{Temperature: &temperature},
+		History: append(newHistory("q1"),
+			genai.NewContentFromFunctionCall("f", map[string]any{}, genai.RoleModel),
+			genai.NewContentFromFunctionResponse("f", map[string]any{}, genai.RoleUser),
+			genai.NewContentFromText("a1", genai.RoleModel),
+			genai.NewContentFromText("q2", genai.RoleUser),
+			genai.NewContentFromText("a2", genai.RoleModel),
+		),
+	}
+	conversation := NewConversation("label", session)
+	conversation.Tokens = 100
+	if err := store.Save(conversation); err != nil {
+		t.Fatal(err)
+	}
+	if conversation.ID == "" || conversation.Created.IsZero() || !conversation.Updated.Equal(conversation.Created) {
+		t.Errorf("unexpected entry %+v", conversation.Entry)
+	}
+	// the function responses are not counted as turns
+	if conversation.Turns != 2 {
+		t.Errorf("turns = %d, want 2", conversation.Turns)
+	}
+
+	loaded, err := store.Load(conversation.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Entry.ID != conversation.ID || loaded.Label != "label" || loaded.Tokens != 100 {
+		t.Errorf("loaded entry = %+v, want %+v", loaded.Entry, conversation.Entry)
+	}
+	restored := loaded.Session()
+	if restored.Model != "model" || restored.SystemPrompt != "brief" ||
+		restored.SystemInstruction.Parts[0].Text != "be brief" ||
+		*restored.GenerationConfig.Temperature != temperature {
+		t.Errorf("unexpected restored session %+v", restored)
+	}
+	if len(restored.History) != len(session.History) || restored.History[2].Parts[0].FunctionResponse == nil {
+		t.Errorf("unexpected restored history %v", restored.History)
+	}
+
+	// the session replacement resets the token count
+	loaded.SetSession(&Session{History: newHistory("q", "a")})
+	if err := store.Save(loaded); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Tokens != 0 || entries[0].Turns != 1 ||
+		!entries[0].Created.Equal(conversation.Created) {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+}
+
+func TestStoreList(t *testing.T) {
+	store := NewStore(t.TempDir())
+	ids := make([]string, 3)
+	for i, label := range []string{"first", "second", "third"} {
+		conversation := NewConversation(label, &Session{History: newHistory("q")})
+		if err := store.Save(conversation); err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = conversation.ID
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the update time of the renamed conversation is preserved
+	if err := store.Rename(ids[0], "renamed"); err != nil {
+		t.Fatal(err)
+	}
+	duplicate, err := store.Duplicate(ids[1], "copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if duplicate.ID == ids[1] || len(duplicate.History) != 1 {
+		t.Errorf("unexpected duplicate %+v", duplicate.Entry)
+	}
+
+	assertLabels(t, store, "copy", "third", "second", "renamed")
+}
+
+func TestStoreDelete(t *testing.T) {
+	store := NewStore(t.TempDir())
+	var ids []string
+	for _, label := range []string{"a", "b", "c"} {
+		conversation := NewConversation(label, &Session{})
+		if err := store.Save(conversation); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, conversation.ID)
+	}
+
+	if err := store.Delete(ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(ids[1]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound loading the deleted conversation, got %v", err)
+	}
+	if err := store.Delete(ids[1]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound deleting twice, got %v", err)
+	}
+	assertLabels(t, store, "c", "a")
+
+	if err := store.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	assertLabels(t, store)
+	files, err := filepath.Glob(filepath.Join(store.Dir(), "*"+fileExtension))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(store.Dir(), indexFileName)}; !slices.Equal(files, want) {
+		t.Errorf("files = %q, want %q", files, want)
+	}
+}
+
+func TestStoreEmptyDir(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "missing"))
+	if err := store.Clear(); err != nil {
+		t.Errorf("unexpected error clearing an empty store: %v", err)
+	}
+	if err := store.Delete("20250101-000000-00000000"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := os.Stat(store.Dir()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the store directory not to be created: %v", err)
+	}
+}
+
+func TestStoreInvalidID(t *testing.T) {
+	store := NewStore(t.TempDir())
+	for _, id := range []string{"", ".", "..", "../index", "a/b"} {
+		if _, err := store.Load(id); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("expected invalid id error loading %q, got %v", id, err)
+		}
+		if err := store.Delete(id); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("expected invalid id error deleting %q, got %v", id, err)
+		}
+	}
+}
+
+func assertLabels(t *testing.T, store *Store, labels ...string) {
+	t.Helper()
+	entries, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := make([]string, len(entries))
+	for i, entry := range entries {
+		actual[i] = entry.Label
+	}
+	if !slices.Equal(actual, labels) {
+		t.Errorf("labels = %q, want %q", actual, labels)
+	}
+}
@@ -0,0 +1,256 @@
+// Package jsonschema implements the validation of JSON values against the
+// commonly used subset of the JSON Schema vocabulary.
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationError represents a mismatch between a JSON value and the schema.
+type ValidationError struct {
+	// Path is the JSON pointer to the mismatching value.
+	Path string
+	// Message describes the mismatch.
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Sprintf("%s: %s", path, e.Message)
+}
+
+// Validate validates the JSON-decoded value against the schema. The supported
+// keywords are type, nullable, enum, const, properties, required,
+// additionalProperties, items, minItems, maxItems, minLength, maxLength, pattern,
+// minimum, maximum, exclusiveMinimum, exclusiveMaximum, anyOf, oneOf and allOf.
+// Other keywords, including references, are ignored.
+func Validate(schema map[string]any, value any) error {
+	return validate(schema, value, "")
+}
+
+// ValidateJSON decodes the JSON data and validates it against the schema.
+func ValidateJSON(schema map[string]any, data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return Validate(schema, value)
+}
+
+//nolint:gocyclo,funlen
+func validate(schema map[string]any, value any, path string) error {
+	fail := func(format string, args ...any) error {
+		return &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)}
+	}
+
+	if value == nil && schema["nullable"] == true {
+		return nil
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		actual := valueType(value)
+		if !slices.Contains(types, actual) &&
+			!(actual == "integer" && slices.Contains(types, "number")) {
+			return fail("expected %s, got %s", strings.Join(types, " or "), actual)
+		}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		if !slices.ContainsFunc(enum, func(e any) bool { return reflect.DeepEqual(e, value) }) {
+			return fail("value is not one of the enumerated values")
+		}
+	}
+	if constant, ok := schema["const"]; ok && !reflect.DeepEqual(constant, value) {
+		return fail("value does not equal the constant")
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		if err := validateObject(schema, v, path); err != nil {
+			return err
+		}
+	case []any:
+		if n, ok := number(schema["minItems"]); ok && float64(len(v)) < n {
+			return fail("expected at least %v items, got %d", n, len(v))
+		}
+		if n, ok := number(schema["maxItems"]); ok && float64(len(v)) > n {
+			return fail("expected at most %v items, got %d", n, len(v))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validate(items, item, fmt.Sprintf("%s/%d", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if n, ok := number(schema["minLength"]); ok && length < n {
+			return fail("expected at least %v characters", n)
+		}
+		if n, ok := number(schema["maxLength"]); ok && length > n {
+			return fail("expected at most %v characters", n)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fail("invalid pattern %q: %s", pattern, err)
+			}
+			if !re.MatchString(v) {
+				return fail("value does not match the pattern %q", pattern)
+			}
+		}
+	case float64:
+		if n, ok := number(schema["minimum"]); ok && v < n {
+			return fail("expected a value >= %v", n)
+		}
+		if n, ok := number(schema["maximum"]); ok && v > n {
+			return fail("expected a value <= %v", n)
+		}
+		if n, ok := number(schema["exclusiveMinimum"]); ok && v <= n {
+			return fail("expected a value > %v", n)
+		}
+		if n, ok := number(schema["exclusiveMaximum"]); ok && v >= n {
+			return fail("expected a value < %v", n)
+		}
+	}
+
+	return validateComposition(schema, value, path)
+}
+
+// validateObject validates the object properties.
+func validateObject(schema map[string]any, object map[string]any, path string) error {
+	if required, ok := schema["required"].([]any); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, ok := object[name]; !ok {
+					return &ValidationError{Path: path, Message: fmt.Sprintf("missing required property %q", name)}
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	for _, name := range slices.Sorted(maps.Keys(object)) {
+		property := object[name]
+		propertyPath := path + "/" + escapePointer(name)
+		if propertySchema, ok := properties[name].(map[string]any); ok {
+			if err := validate(propertySchema, property, propertyPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return &ValidationError{Path: path, Message: fmt.Sprintf("unexpected property %q", name)}
+			}
+		case map[string]any:
+			if err := validate(additional, property, propertyPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateComposition validates the value against the composed subschemas.
+func validateComposition(schema map[string]any, value any, path string) error {
+	if subschemas, ok := schema["allOf"].([]any); ok {
+		for _, subschema := range subschemas {
+			if subschema, ok := subschema.(map[string]any); ok {
+				if err := validate(subschema, value, path); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if subschemas, ok := schema["anyOf"].([]any); ok && matches(subschemas, value, path) == 0 {
+		return &ValidationError{Path: path, Message: "value does not match any of the schemas"}
+	}
+	if subschemas, ok := schema["oneOf"].([]any); ok && matches(subschemas, value, path) != 1 {
+		return &ValidationError{Path: path, Message: "value does not match exactly one of the schemas"}
+	}
+
+	return nil
+}
+
+// matches returns the number of the subschemas the value is valid against.
+func matches(subschemas []any, value any, path string) int {
+	var count int
+	for _, subschema := range subschemas {
+		if subschema, ok := subschema.(map[string]any); ok {
+			var validationErr *ValidationError
+			if err := validate(subschema, value, path); !errors.As(err, &validationErr) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// schemaTypes returns the types allowed by the type keyword value.
+func schemaTypes(value any) []string {
+	switch t := value.(type) {
+	case string:
+		return []string{strings.ToLower(t)}
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, strings.ToLower(s))
+			}
+		}
+		return types
+	default:
+		return nil
+	}
+}
+
+// valueType returns the JSON Schema type of the JSON-decoded value.
+func valueType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func number(value any) (float64, bool) {
+	n, ok := value.(float64)
+	return n, ok
+}
+
+// escapePointer escapes the JSON pointer reference token.
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,90 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestValidateJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		data   string
+		// path is the path of the expected validation error, or "-" if the
+		// value is valid
+		path string
+	}{
+		{"type string", `{"type": "string"}`, `"a"`, "-"},
+		{"type mismatch", `{"type": "string"}`, `1`, ""},
+		{"type integer", `{"type": "integer"}`, `2`, "-"},
+		{"type integer fraction", `{"type": "integer"}`, `2.5`, ""},
+		{"type number integer", `{"type": "number"}`, `2`, "-"},
+		{"type uppercase", `{"type": "OBJECT"}`, `{}`, "-"},
+		{"type list", `{"type": ["string", "null"]}`, `null`, "-"},
+		{"type list mismatch", `{"type": ["string", "null"]}`, `true`, ""},
+		{"nullable", `{"type": "string", "nullable": true}`, `null`, "-"},
+
+		{"required", `{"type": "object", "required": ["a"]}`, `{"a": 1}`, "-"},
+		{"required missing", `{"type": "object", "required": ["a", "b"]}`, `{"a": 1}`, ""},
+		{"property type", `{"properties": {"a": {"type": "string"}}}`, `{"a": 1}`, "/a"},
+		{"property escaped", `{"properties": {"a/b": {"type": "string"}}}`, `{"a/b": 1}`, "/a~1b"},
+		{"nested required", `{"properties": {"a": {"required": ["b"]}}}`, `{"a": {}}`, "/a"},
+		{"additional properties", `{"properties": {"a": {}}, "additionalProperties": false}`, `{"b": 1}`, ""},
+		{"additional schema", `{"additionalProperties": {"type": "integer"}}`, `{"b": "x"}`, "/b"},
+		{"items", `{"items": {"type": "integer"}}`, `[1, 2, "3"]`, "/2"},
+
+		{"enum", `{"enum": ["a", "b"]}`, `"b"`, "-"},
+		{"enum mismatch", `{"enum": ["a", "b"]}`, `"c"`, ""},
+		{"enum object", `{"enum": [{"a": 1}]}`, `{"a": 1}`, "-"},
+		{"const", `{"const": 1}`, `2`, ""},
+
+		{"anyOf", `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, `1`, "-"},
+		{"anyOf mismatch", `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, `true`, ""},
+		{"oneOf", `{"oneOf": [{"type": "integer"}, {"type": "number"}]}`, `1.5`, "-"},
+		{"oneOf ambiguous", `{"oneOf": [{"type": "integer"}, {"type": "number"}]}`, `1`, ""},
+		{"allOf", `{"allOf": [{"minimum": 1}, {"maximum": 2}]}`, `3`, ""},
+
+		{"minimum", `{"minimum": 1}`, `1`, "-"},
+		{"minimum below", `{"minimum": 1}`, `0.5`, ""},
+		{"maximum above", `{"maximum": 1}`, `2`, ""},
+		{"exclusive minimum", `{"exclusiveMinimum": 1}`, `1`, ""},
+		{"exclusive maximum", `{"exclusiveMaximum": 1}`, `0.9`, "-"},
+		{"min length runes", `{"minLength": 2}`, `"é"`, ""},
+		{"max length runes", `{"maxLength": 2}`, `"éé"`, "-"},
+		{"pattern", `{"pattern": "^[a-z]+$"}`, `"abc1"`, ""},
+		{"min items", `{"minItems": 1}`, `[]`, ""},
+		{"max items", `{"maxItems": 1}`, `[1, 2]`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var schema map[string]any
+			if err := json.Unmarshal([]byte(tt.schema), &schema); err != nil {
+				t.Fatal(err)
+			}
+
+			err := ValidateJSON(schema, []byte(tt.data))
+			if tt.path == "-" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected validation error, got %v", err)
+			}
+			if validationErr.Path != tt.path {
+				t.Errorf("error path = %q, want %q", validationErr.Path, tt.path)
+			}
+		})
+	}
+}
+
+func TestValidateJSONInvalid(t *testing.T) {
+	err := ValidateJSON(map[string]any{}, []byte(`{"a": `))
+	var validationErr *ValidationError
+	if err == nil || errors.As(err, &validationErr) {
+		t.Errorf("expected decoding error, got %v", err)
+	}
+}
@@ -0,0 +1,361 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	protocolVersion = "2025-06-18"
+	jsonRPCVersion  = "2.0"
+
+	// maxMessageSize is the maximum size of a message received from the server.
+	maxMessageSize = 16 << 20
+	// closeTimeout is the time given to the server to exit once its standard
+	// input is closed, before it is killed.
+	closeTimeout = 3 * time.Second
+
+	errCodeMethodNotFound = -32601
+)
+
+// Implementation describes an MCP client or server implementation.
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Tool represents a tool provided by an MCP server.
+type Tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"inputSchema,omitempty"`
+}
+
+// Content represents an item of the tool call result content.
+type Content struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	MIMEType string `json:"mimeType,omitempty"`
+}
+
+// CallToolResult represents the result of a tool call.
+type CallToolResult struct {
+	Content           []Content      `json:"content"`
+	StructuredContent map[string]any `json:"structuredContent,omitempty"`
+	IsError           bool           `json:"isError,omitempty"`
+}
+
+// Text returns the text of the result content items, separated by newlines.
+// Non-text items are represented by their type.
+func (r *CallToolResult) Text() string {
+	texts := make([]string, len(r.Content))
+	for i, content := range r.Content {
+		if content.Type == "text" {
+			texts[i] = content.Text
+		} else {
+			texts[i] = fmt.Sprintf("[%s content]", content.Type)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+type request struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      *int64 `json:"id,omitempty"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// message represents a message received from the server, which is either
+// a response, a request or a notification.
+type message struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// Client is a Model Context Protocol client, communicating with a server
+// launched as a subprocess using the stdio transport.
+type Client struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	server Implementation
+
+	writeMu sync.Mutex
+	nextID  atomic.Int64
+
+	pendingMu sync.Mutex
+	pending   map[int64]chan *message
+
+	done    chan struct{}
+	readErr error
+}
+
+// Start launches the server command, and initializes the client session.
+// The server environment consists of the current process environment and
+// the given variables.
+func Start(ctx context.Context, client Implementation, command string, args []string,
+	env map[string]string) (*Client, error) {
+	cmd := exec.Command(command, args...) //nolint:gosec // the server command is user-configured
+	detach(cmd)
+	cmd.Env = os.Environ()
+	for name, value := range env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start server: %w", err)
+	}
+
+	c := &Client{
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[int64]chan *message),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(stdout)
+
+	if err := c.initialize(ctx, client); err != nil {
+		return nil, errors.Join(err, c.Close())
+	}
+
+	return c, nil
+}
+
+// Server returns the server implementation information.
+func (c *Client) Server() Implementation {
+	return c.server
+}
+
+// ListTools returns the tools provided by the server.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var (
+		tools  []Tool
+		cursor string
+	)
+	for {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+
+		var result struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor,omitempty"`
+		}
+		if err := c.call(ctx, "tools/list", params, &result); err != nil {
+			return nil, fmt.Errorf("failed to list tools: %w", err)
+		}
+
+		tools = append(tools, result.Tools...)
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// CallTool calls the named tool with the given arguments.
+func (c *Client) CallTool(ctx context.Context, name string,
+	args map[string]any) (*CallToolResult, error) {
+	if args == nil {
+		args = make(map[string]any)
+	}
+
+	params := map[string]any{
+		"name":      name,
+		"arguments": args,
+	}
+	result := &CallToolResult{}
+	if err := c.call(ctx, "tools/call", params, result); err != nil {
+		return nil, fmt.Errorf("failed to call tool %s: %w", name, err)
+	}
+
+	return result, nil
+}
+
+// Close closes the standard input of the server, and waits for it to exit.
+// The server is killed if it does not exit in time.
+func (c *Client) Close() error {
+	_ = c.stdin.Close()
+
+	select {
+	case <-c.done:
+	case <-time.After(closeTimeout):
+		_ = c.cmd.Process.Kill()
+	}
+
+	var exitErr *exec.ExitError
+	if err := c.cmd.Wait(); err != nil && !errors.As(err, &exitErr) {
+		return err
+	}
+	return nil
+}
+
+// initialize performs the session initialization handshake.
+func (c *Client) initialize(ctx context.Context, client Implementation) error {
+	params := map[string]any{
+		"protocolVersion": protocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      client,
+	}
+
+	var result struct {
+		ProtocolVersion string         `json:"protocolVersion"`
+		ServerInfo      Implementation `json:"serverInfo"`
+	}
+	if err := c.call(ctx, "initialize", params, &result); err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+	c.server = result.ServerInfo
+
+	return c.notify("notifications/initialized", nil)
+}
+
+// call sends the request and decodes the response result.
+func (c *Client) call(ctx context.Context, method string, params, result any) error {
+	id := c.nextID.Add(1)
+	responses := make(chan *message, 1)
+
+	c.pendingMu.Lock()
+	c.pending[id] = responses
+	c.pendingMu.Unlock()
+
+	defer func() {
+		c.pendingMu.Lock()
+		delete(c.pending, id)
+		c.pendingMu.Unlock()
+	}()
+
+	if err := c.write(request{JSONRPC: jsonRPCVersion, ID: &id, Method: method, Params: params}); err != nil {
+		return err
+	}
+
+	select {
+	case response := <-responses:
+		if response.Error != nil {
+			return response.Error
+		}
+		return json.Unmarshal(response.Result, result)
+	case <-c.done:
+		return fmt.Errorf("server exited: %w", c.readErr)
+	case <-ctx.Done():
+		_ = c.notify("notifications/cancelled", map[string]any{
+			"requestId": id,
+			"reason":    ctx.Err().Error(),
+		})
+		return ctx.Err()
+	}
+}
+
+// notify sends the notification.
+func (c *Client) notify(method string, params any) error {
+	return c.write(request{JSONRPC: jsonRPCVersion, Method: method, Params: params})
+}
+
+// write writes the message to the server standard input as a single line.
+func (c *Client) write(message any) error {
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("error encoding message: %w", err)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if _, err := c.stdin.Write(append(encoded, '\n')); err != nil {
+		return fmt.Errorf("error writing message: %w", err)
+	}
+	return nil
+}
+
+// readLoop reads the server messages until the standard output is closed,
+// delivering the responses to the pending requests.
+func (c *Client) readLoop(stdout io.Reader) {
+	defer close(c.done)
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		msg := &message{}
+		if err := json.Unmarshal(scanner.Bytes(), msg); err != nil {
+			continue // skip non-protocol output
+		}
+
+		if msg.Method != "" {
+			c.handleServerMessage(msg)
+			continue
+		}
+
+		var id int64
+		if err := json.Unmarshal(msg.ID, &id); err != nil {
+			continue
+		}
+
+		c.pendingMu.Lock()
+		responses, ok := c.pending[id]
+		c.pendingMu.Unlock()
+		if ok {
+			responses <- msg
+		}
+	}
+
+	c.readErr = scanner.Err()
+	if c.readErr == nil {
+		c.readErr = io.EOF
+	}
+}
+
+// handleServerMessage responds to the server requests. Only the ping request
+// is supported; notifications are ignored.
+func (c *Client) handleServerMessage(msg *message) {
+	if msg.ID == nil {
+		return
+	}
+
+	resp := response{JSONRPC: jsonRPCVersion, ID: msg.ID}
+	if msg.Method == "ping" {
+		resp.Result = map[string]any{}
+	} else {
+		resp.Error = &rpcError{
+			Code:    errCodeMethodNotFound,
+			Message: "method not found: " + msg.Method,
+		}
+	}
+	_ = c.write(resp)
+}
@@ -0,0 +1,222 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServerEnv is the environment variable making the test binary act as
+// a fake MCP server communicating over the standard input and output.
+const fakeServerEnv = "MCP_FAKE_SERVER"
+
+var testClient = Implementation{Name: "test-client", Version: "1.0.0"}
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeServerEnv) != "" {
+		runFakeServer()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestClient(t *testing.T) {
+	client := startFakeServer(t)
+
+	if server := client.Server(); server.Name != "fake" || server.Version != "0.1.0" {
+		t.Errorf("server = %+v", server)
+	}
+
+	tools, err := client.ListTools(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name
+	}
+	// the tools are listed across two pages
+	if want := []string{"echo", "fail", "web.search"}; !slices.Equal(names, want) {
+		t.Errorf("tools = %q, want %q", names, want)
+	}
+
+	result, err := client.CallTool(context.Background(), "echo", map[string]any{"text": "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsError || result.Text() != "hello\n[image content]" {
+		t.Errorf("echo result = %+v", result)
+	}
+
+	result, err = client.CallTool(context.Background(), "fail", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsError || result.Text() != "tool failed" {
+		t.Errorf("fail result = %+v", result)
+	}
+
+	_, err = client.CallTool(context.Background(), "unknown", nil)
+	var rpcErr *rpcError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != -32602 {
+		t.Errorf("expected the invalid params error reply, got %v", err)
+	}
+}
+
+func TestClientCanceled(t *testing.T) {
+	client := startFakeServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.CallTool(ctx, "sleep", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	// the client remains usable after the cancellation
+	if _, err := client.CallTool(context.Background(), "echo", map[string]any{"text": "a"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStartError(t *testing.T) {
+	_, err := Start(context.Background(), testClient, os.Args[0], nil,
+		map[string]string{fakeServerEnv: "exit"})
+	if err == nil {
+		t.Error("expected initialization error")
+	}
+}
+
+// startFakeServer starts the test binary as a fake MCP server.
+func startFakeServer(t *testing.T) *Client {
+	t.Helper()
+	client, err := Start(context.Background(), testClient, os.Args[0], nil,
+		map[string]string{fakeServerEnv: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := client.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return client
+}
+
+// runFakeServer serves the MCP requests read from the standard input,
+// until it is closed.
+func runFakeServer() {
+	if os.Getenv(fakeServerEnv) == "exit" {
+		return
+	}
+
+	var mu sync.Mutex
+	writer := bufio.NewWriter(os.Stdout)
+	send := func(message any) {
+		mu.Lock()
+		defer mu.Unlock()
+		data, _ := json.Marshal(message)
+		_, _ = writer.Write(append(data, '\n'))
+		_ = writer.Flush()
+	}
+	// the output not following the protocol is skipped by the client
+	fmt.Fprintln(writer, "fake server starting")
+	// the client responds to the server requests
+	send(map[string]any{"jsonrpc": jsonRPCVersion, "id": "s1", "method": "ping"})
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params struct {
+				Cursor    string         `json:"cursor"`
+				Name      string         `json:"name"`
+				Arguments map[string]any `json:"arguments"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil || req.ID == nil || req.Method == "" {
+			// notifications and the responses to the server requests
+			continue
+		}
+
+		respond := func() {
+			result, rpcErr := fakeServerResult(req.Method, req.Params.Cursor, req.Params.Name,
+				req.Params.Arguments)
+			resp := map[string]any{"jsonrpc": jsonRPCVersion, "id": req.ID}
+			if rpcErr != nil {
+				resp["error"] = rpcErr
+			} else {
+				resp["result"] = result
+			}
+			send(resp)
+		}
+		if req.Params.Name == "sleep" {
+			// the response arrives after the request is canceled
+			go func() {
+				time.Sleep(time.Second)
+				respond()
+			}()
+			continue
+		}
+		respond()
+	}
+}
+
+// fakeServerResult returns the result of the fake server request.
+func fakeServerResult(method, cursor, tool string, args map[string]any) (any, *rpcError) {
+	switch method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": protocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      Implementation{Name: "fake", Version: "0.1.0"},
+		}, nil
+	case "tools/list":
+		if cursor == "" {
+			return map[string]any{
+				"tools": []Tool{{
+					Name:        "echo",
+					Description: "Echoes the text.",
+					InputSchema: map[string]any{
+						"$schema":    "http://json-schema.org/draft-07/schema#",
+						"type":       "object",
+						"properties": map[string]any{"text": map[string]any{"type": "string"}},
+					},
+				}},
+				"nextCursor": "page2",
+			}, nil
+		}
+		return map[string]any{"tools": []Tool{{Name: "fail"}, {Name: "web.search"}}}, nil
+	case "tools/call":
+		switch tool {
+		case "echo":
+			text, _ := args["text"].(string)
+			return CallToolResult{Content: []Content{
+				{Type: "text", Text: text},
+				{Type: "image", MIMEType: "image/png"},
+			}}, nil
+		case "fail":
+			return CallToolResult{Content: []Content{{Type: "text", Text: "tool failed"}}, IsError: true}, nil
+		case "sleep":
+			return CallToolResult{Content: []Content{{Type: "text", Text: "late"}}}, nil
+		case "web.search":
+			query, _ := args["query"].(string)
+			return CallToolResult{
+				Content:           []Content{{Type: "text", Text: "results"}},
+				StructuredContent: map[string]any{"results": strings.Fields(query)},
+			}, nil
+		default:
+			return nil, &rpcError{Code: -32602, Message: "unknown tool: " + tool}
+		}
+	default:
+		return nil, &rpcError{Code: errCodeMethodNotFound, Message: "method not found: " + method}
+	}
+}
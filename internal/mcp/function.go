@@ -0,0 +1,82 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"regexp"
+
+	"github.com/reugn/gemini-cli/gemini"
+	"google.golang.org/genai"
+)
+
+const maxFunctionNameLength = 64
+
+var invalidFunctionNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// Function is a gemini.Function calling an MCP server tool.
+type Function struct {
+	client      *Client
+	tool        Tool
+	declaration *genai.FunctionDeclaration
+}
+
+var _ gemini.Function = (*Function)(nil)
+
+// newFunction returns a new Function for the server tool. The function name
+// is prefixed with the server name to avoid conflicts between the servers.
+func newFunction(server string, client *Client, tool Tool) *Function {
+	declaration := &genai.FunctionDeclaration{
+		Name:        functionName(server, tool.Name),
+		Description: tool.Description,
+	}
+	if tool.InputSchema != nil {
+		schema := maps.Clone(tool.InputSchema)
+		delete(schema, "$schema")
+		declaration.ParametersJsonSchema = schema
+	}
+
+	return &Function{
+		client:      client,
+		tool:        tool,
+		declaration: declaration,
+	}
+}
+
+// Tool returns the server tool called by the function.
+func (f *Function) Tool() Tool {
+	return f.tool
+}
+
+// Declaration returns the function declaration provided to the model.
+func (f *Function) Declaration() *genai.FunctionDeclaration {
+	return f.declaration
+}
+
+// Call calls the server tool. The structured content of the result is
+// returned if available; otherwise, the text content is returned as the
+// "output" field.
+func (f *Function) Call(ctx context.Context, args map[string]any) (map[string]any, error) {
+	result, err := f.client.CallTool(ctx, f.tool.Name, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.IsError {
+		return nil, errors.New(result.Text())
+	}
+	if result.StructuredContent != nil {
+		return result.StructuredContent, nil
+	}
+
+	return map[string]any{"output": result.Text()}, nil
+}
+
+// functionName returns a valid function name for the server tool.
+func functionName(server, tool string) string {
+	name := invalidFunctionNameChars.ReplaceAllString(server+"_"+tool, "_")
+	if len(name) > maxFunctionNameLength {
+		name = name[:maxFunctionNameLength]
+	}
+	return name
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package mcp
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach starts the command in a new process group, so that the interrupt
+// signal sent to cancel a model request does not terminate the server.
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
@@ -0,0 +1,143 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/reugn/gemini-cli/gemini"
+	"github.com/reugn/gemini-cli/internal/config"
+)
+
+// connectTimeout is the time given to a server to start and list its tools.
+const connectTimeout = 30 * time.Second
+
+// Server represents a configured MCP server.
+type Server struct {
+	// The server name from the configuration.
+	Name string
+	// The server connection error, if the connection failed.
+	Err error
+
+	client      *Client
+	functions   []*Function
+	autoApprove bool
+}
+
+// Functions returns the functions calling the server tools.
+func (s *Server) Functions() []*Function {
+	return s.functions
+}
+
+// Client returns the server client, or nil if the connection failed.
+func (s *Server) Client() *Client {
+	return s.client
+}
+
+// Servers represents the configured MCP servers.
+type Servers []*Server
+
+// Connect launches the enabled servers concurrently and discovers their tools.
+// The servers that fail to connect are returned with the connection error.
+func Connect(ctx context.Context, client Implementation,
+	servers map[string]config.MCPServer) Servers {
+	var (
+		connected Servers
+		wg        sync.WaitGroup
+	)
+	for _, name := range slices.Sorted(maps.Keys(servers)) {
+		serverConfig := servers[name]
+		if !serverConfig.Enabled {
+			continue
+		}
+
+		server := &Server{Name: name, autoApprove: serverConfig.AutoApprove}
+		connected = append(connected, server)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+			defer cancel()
+
+			server.Err = server.connect(ctx, client, serverConfig)
+		}()
+	}
+	wg.Wait()
+
+	return connected
+}
+
+// connect starts the server and discovers its tools.
+func (s *Server) connect(ctx context.Context, client Implementation,
+	serverConfig config.MCPServer) error {
+	c, err := Start(ctx, client, serverConfig.Command, serverConfig.Args, serverConfig.Env)
+	if err != nil {
+		return err
+	}
+
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		return errors.Join(err, c.Close())
+	}
+
+	s.client = c
+	s.functions = make([]*Function, len(tools))
+	for i, tool := range tools {
+		s.functions[i] = newFunction(s.Name, c, tool)
+	}
+
+	return nil
+}
+
+// Functions returns the functions calling the tools of the connected servers.
+func (s Servers) Functions() []gemini.Function {
+	var functions []gemini.Function
+	for _, server := range s {
+		for _, function := range server.functions {
+			functions = append(functions, function)
+		}
+	}
+	return functions
+}
+
+// ApprovedFunctions returns the names of the functions of the servers,
+// whose tools are called without confirmation.
+func (s Servers) ApprovedFunctions() map[string]bool {
+	approved := make(map[string]bool)
+	for _, server := range s {
+		if !server.autoApprove {
+			continue
+		}
+		for _, function := range server.functions {
+			approved[function.Declaration().Name] = true
+		}
+	}
+	return approved
+}
+
+// Errors returns the connection errors of the servers.
+func (s Servers) Errors() error {
+	var errs []error
+	for _, server := range s {
+		if server.Err != nil {
+			errs = append(errs, fmt.Errorf("mcp server %s: %w", server.Name, server.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes the connected servers.
+func (s Servers) Close() error {
+	var errs []error
+	for _, server := range s {
+		if server.client != nil {
+			errs = append(errs, server.client.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,126 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/reugn/gemini-cli/internal/config"
+)
+
+func TestConnect(t *testing.T) {
+	fakeServer := config.MCPServer{
+		Command: os.Args[0],
+		Env:     map[string]string{fakeServerEnv: "1"},
+		Enabled: true,
+	}
+	approvedServer := fakeServer
+	approvedServer.AutoApprove = true
+	disabledServer := fakeServer
+	disabledServer.Enabled = false
+
+	servers := Connect(context.Background(), testClient, map[string]config.MCPServer{
+		"fake":     fakeServer,
+		"my.tools": approvedServer,
+		"disabled": disabledServer,
+		"missing":  {Command: "/nonexistent/mcp-server", Enabled: true},
+	})
+	t.Cleanup(func() {
+		if err := servers.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	names := make([]string, len(servers))
+	for i, server := range servers {
+		names[i] = server.Name
+	}
+	if want := []string{"fake", "missing", "my.tools"}; !slices.Equal(names, want) {
+		t.Fatalf("servers = %q, want %q", names, want)
+	}
+	if servers[1].Err == nil || servers[1].Client() != nil {
+		t.Error("expected the connection error of the missing server")
+	}
+	if err := servers.Errors(); err == nil || !strings.Contains(err.Error(), "mcp server missing") {
+		t.Errorf("unexpected connection errors: %v", err)
+	}
+
+	// the function names are prefixed with the server name
+	var functionNames []string
+	for _, function := range servers.Functions() {
+		functionNames = append(functionNames, function.Declaration().Name)
+	}
+	want := []string{
+		"fake_echo", "fake_fail", "fake_web_search",
+		"my_tools_echo", "my_tools_fail", "my_tools_web_search",
+	}
+	if !slices.Equal(functionNames, want) {
+		t.Errorf("functions = %q, want %q", functionNames, want)
+	}
+
+	approved := servers.ApprovedFunctions()
+	if want := map[string]bool{
+		"my_tools_echo": true, "my_tools_fail": true, "my_tools_web_search": true,
+	}; !reflect.DeepEqual(approved, want) {
+		t.Errorf("approved functions = %v, want %v", approved, want)
+	}
+
+	echo := servers[0].Functions()[0]
+	if _, ok := echo.Declaration().ParametersJsonSchema.(map[string]any)["$schema"]; ok {
+		t.Error("expected the $schema keyword to be removed from the parameters")
+	}
+	if echo.Tool().Name != "echo" {
+		t.Errorf("tool name = %q, want %q", echo.Tool().Name, "echo")
+	}
+}
+
+func TestFunctionCall(t *testing.T) {
+	client := startFakeServer(t)
+	tests := []struct {
+		tool string
+		args map[string]any
+		// output is the expected function output, or nil if the call fails
+		output map[string]any
+	}{
+		{"echo", map[string]any{"text": "hi"}, map[string]any{"output": "hi\n[image content]"}},
+		{"web.search", map[string]any{"query": "a b"}, map[string]any{"results": []any{"a", "b"}}},
+		{"fail", nil, nil},
+		{"unknown", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			function := newFunction("fake", client, Tool{Name: tt.tool})
+			output, err := function.Call(context.Background(), tt.args)
+			if tt.output == nil {
+				if err == nil {
+					t.Errorf("expected error, got %v", output)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(output, tt.output) {
+				t.Errorf("output = %v, want %v", output, tt.output)
+			}
+		})
+	}
+}
+
+func TestFunctionName(t *testing.T) {
+	tests := []struct {
+		server, tool, name string
+	}{
+		{"files", "read_file", "files_read_file"},
+		{"my server", "web.search/v2", "my_server_web_search_v2"},
+		{"s", strings.Repeat("t", 80), "s_" + strings.Repeat("t", maxFunctionNameLength-2)},
+	}
+	for _, tt := range tests {
+		if name := functionName(tt.server, tt.tool); name != tt.name {
+			t.Errorf("functionName(%q, %q) = %q, want %q", tt.server, tt.tool, name, tt.name)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package openai
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+const jsonMIMEType = "application/json"
+
+// newChatRequest converts the contents and the generation config to a chat
+// completion request. The built-in tools are not supported and are skipped.
+func newChatRequest(model string, contents []*genai.Content,
+	config *genai.GenerateContentConfig) (*chatRequest, error) {
+	request := &chatRequest{Model: model}
+
+	if config != nil && config.SystemInstruction != nil {
+		if text := contentText(config.SystemInstruction); text != "" {
+			request.Messages = append(request.Messages, &message{Role: roleSystem, Content: text})
+		}
+	}
+
+	var pendingCallIDs []string
+	for i, content := range contents {
+		messages, callIDs, err := contentMessages(content, i, pendingCallIDs)
+		if err != nil {
+			return nil, err
+		}
+		request.Messages = append(request.Messages, messages...)
+		pendingCallIDs = callIDs
+	}
+
+	if config != nil {
+		applyConfig(request, config)
+	}
+
+	return request, nil
+}
+
+// applyConfig applies the generation parameters, the function declarations
+// and the response format to the request.
+func applyConfig(request *chatRequest, config *genai.GenerateContentConfig) {
+	request.Temperature = config.Temperature
+	request.TopP = config.TopP
+	request.MaxTokens = config.MaxOutputTokens
+	request.Stop = config.StopSequences
+	request.Seed = config.Seed
+	if config.CandidateCount > 1 {
+		request.N = config.CandidateCount
+	}
+
+	for _, t := range config.Tools {
+		for _, declaration := range t.FunctionDeclarations {
+			parameters := declaration.ParametersJsonSchema
+			if parameters == nil {
+				parameters = map[string]any{"type": "object", "properties": map[string]any{}}
+			}
+			request.Tools = append(request.Tools, &tool{
+				Type: "function",
+				Function: &functionDef{
+					Name:        declaration.Name,
+					Description: declaration.Description,
+					Parameters:  parameters,
+				},
+			})
+		}
+	}
+
+	if config.ResponseMIMEType == jsonMIMEType {
+		request.ResponseFormat = &responseFormat{Type: "json_object"}
+		if config.ResponseJsonSchema != nil {
+			request.ResponseFormat = &responseFormat{
+				Type:       "json_schema",
+				JSONSchema: &jsonSchema{Name: "response", Schema: config.ResponseJsonSchema},
+			}
+		}
+	}
+}
+
+// contentMessages converts the content to chat messages. The function
+// responses are matched to the calls of the preceding assistant message by
+// their IDs, or by order if the IDs are not set. It returns the IDs of the
+// function calls in the content.
+//
+//nolint:gocyclo
+func contentMessages(content *genai.Content, index int,
+	pendingCallIDs []string) ([]*message, []string, error) {
+	if content.Role == genai.RoleModel {
+		assistant := &message{Role: roleAssistant}
+		var (
+			text    strings.Builder
+			callIDs []string
+		)
+		for i, part := range content.Parts {
+			switch {
+			case part.Thought:
+				// the thoughts are not sent back to the model
+			case part.FunctionCall != nil:
+				arguments, err := json.Marshal(part.FunctionCall.Args)
+				if err != nil {
+					return nil, nil, fmt.Errorf("error encoding function call: %w", err)
+				}
+				id := part.FunctionCall.ID
+				if id == "" {
+					id = fmt.Sprintf("call_%d_%d", index, i)
+				}
+				callIDs = append(callIDs, id)
+				assistant.ToolCalls = append(assistant.ToolCalls, &toolCall{
+					ID:   id,
+					Type: "function",
+					Function: functionCall{
+						Name:      part.FunctionCall.Name,
+						Arguments: string(arguments),
+					},
+				})
+			default:
+				text.WriteString(part.Text)
+			}
+		}
+		if text.Len() > 0 {
+			assistant.Content = text.String()
+		}
+		return []*message{assistant}, callIDs, nil
+	}
+
+	var (
+		messages []*message
+		parts    []*contentPart
+	)
+	for _, part := range content.Parts {
+		switch {
+		case part.FunctionResponse != nil:
+			response, err := json.Marshal(part.FunctionResponse.Response)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error encoding function response: %w", err)
+			}
+			id := part.FunctionResponse.ID
+			if id == "" && len(pendingCallIDs) > 0 {
+				id = pendingCallIDs[0]
+			}
+			if len(pendingCallIDs) > 0 {
+				pendingCallIDs = pendingCallIDs[1:]
+			}
+			messages = append(messages, &message{
+				Role:       roleTool,
+				ToolCallID: id,
+				Content:    string(response),
+			})
+		case part.InlineData != nil:
+			part, err := blobPart(part.InlineData)
+			if err != nil {
+				return nil, nil, err
+			}
+			parts = append(parts, part)
+		case part.Text != "":
+			parts = append(parts, &contentPart{Type: "text", Text: part.Text})
+		}
+	}
+
+	if len(parts) > 0 {
+		messages = append(messages, &message{Role: roleUser, Content: userContent(parts)})
+	}
+	return messages, nil, nil
+}
+
+// blobPart converts the inline data to a content part. Images are sent as
+// data URLs, and text files as text; other types are not supported.
+func blobPart(blob *genai.Blob) (*contentPart, error) {
+	switch {
+	case strings.HasPrefix(blob.MIMEType, "image/"):
+		url := fmt.Sprintf("data:%s;base64,%s", blob.MIMEType,
+			base64.StdEncoding.EncodeToString(blob.Data))
+		return &contentPart{Type: "image_url", ImageURL: &imageURL{URL: url}}, nil
+	case strings.HasPrefix(blob.MIMEType, "text/") || blob.MIMEType == jsonMIMEType:
+		return &contentPart{Type: "text", Text: string(blob.Data)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported attachment type: %s", blob.MIMEType)
+	}
+}
+
+// userContent returns the user message content, which is a string if it
+// consists of a single text part.
+func userContent(parts []*contentPart) any {
+	if len(parts) == 1 && parts[0].Type == "text" {
+		return parts[0].Text
+	}
+	return parts
+}
+
+// contentText returns the concatenated text of the content parts.
+func contentText(content *genai.Content) string {
+	var b strings.Builder
+	for _, part := range content.Parts {
+		b.WriteString(part.Text)
+	}
+	return b.String()
+}
+
+// newResponse converts the chat completion response.
+func newResponse(response *chatResponse) (*genai.GenerateContentResponse, error) {
+	result := &genai.GenerateContentResponse{UsageMetadata: usageMetadata(response.Usage)}
+	for _, choice := range response.Choices {
+		if choice.Message == nil {
+			continue
+		}
+
+		content := &genai.Content{Role: genai.RoleModel}
+		if text := choice.Message.Content + choice.Message.Refusal; text != "" {
+			content.Parts = append(content.Parts, genai.NewPartFromText(text))
+		}
+		for _, call := range choice.Message.ToolCalls {
+			part, err := functionCallPart(call)
+			if err != nil {
+				return nil, err
+			}
+			content.Parts = append(content.Parts, part)
+		}
+
+		result.Candidates = append(result.Candidates, &genai.Candidate{
+			Index:        choice.Index,
+			Content:      content,
+			FinishReason: finishReason(choice.FinishReason),
+		})
+	}
+
+	return result, nil
+}
+
+// functionCallPart converts the tool call to a function call part.
+func functionCallPart(call *toolCall) (*genai.Part, error) {
+	var args map[string]any
+	if arguments := strings.TrimSpace(call.Function.Arguments); arguments != "" {
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return nil, fmt.Errorf("error decoding function call %s arguments: %w",
+				call.Function.Name, err)
+		}
+	}
+
+	return &genai.Part{FunctionCall: &genai.FunctionCall{
+		ID:   call.ID,
+		Name: call.Function.Name,
+		Args: args,
+	}}, nil
+}
+
+// finishReason converts the chat completion finish reason.
+func finishReason(reason string) genai.FinishReason {
+	switch reason {
+	case "":
+		return genai.FinishReasonUnspecified
+	case "stop", "tool_calls", "function_call":
+		return genai.FinishReasonStop
+	case "length":
+		return genai.FinishReasonMaxTokens
+	case "content_filter":
+		return genai.FinishReasonSafety
+	default:
+		return genai.FinishReasonOther
+	}
+}
+
+// usageMetadata converts the token usage, where the reasoning tokens are
+// reported as thoughts.
+func usageMetadata(u *usage) *genai.GenerateContentResponseUsageMetadata {
+	if u == nil {
+		return nil
+	}
+
+	metadata := &genai.GenerateContentResponseUsageMetadata{
+		PromptTokenCount:     u.PromptTokens,
+		CandidatesTokenCount: u.CompletionTokens,
+		TotalTokenCount:      u.TotalTokens,
+	}
+	if u.PromptTokensDetails != nil {
+		metadata.CachedContentTokenCount = u.PromptTokensDetails.CachedTokens
+	}
+	if u.CompletionTokensDetails != nil {
+		metadata.ThoughtsTokenCount = u.CompletionTokensDetails.ReasoningTokens
+		metadata.CandidatesTokenCount -= metadata.ThoughtsTokenCount
+	}
+
+	return metadata
+}
@@ -0,0 +1,212 @@
+package openai
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestNewChatRequestToolCallIDs(t *testing.T) {
+	functionCall := func(id, name string) *genai.Part {
+		return &genai.Part{FunctionCall: &genai.FunctionCall{ID: id, Name: name, Args: map[string]any{"q": name}}}
+	}
+	functionResponse := func(id, name string) *genai.Part {
+		return &genai.Part{FunctionResponse: &genai.FunctionResponse{
+			ID: id, Name: name, Response: map[string]any{"output": name},
+		}}
+	}
+
+	tests := []struct {
+		name      string
+		calls     []*genai.Part
+		responses []*genai.Part
+		callIDs   []string
+		// responseIDs are the tool call IDs of the tool messages in order
+		responseIDs []string
+	}{
+		{
+			"with ids",
+			[]*genai.Part{functionCall("id1", "a"), functionCall("id2", "b")},
+			[]*genai.Part{functionResponse("id2", "b"), functionResponse("id1", "a")},
+			[]string{"id1", "id2"},
+			[]string{"id2", "id1"},
+		},
+		{
+			"without ids",
+			[]*genai.Part{functionCall("", "a"), functionCall("", "b"), functionCall("", "c")},
+			[]*genai.Part{functionResponse("", "a"), functionResponse("", "b"), functionResponse("", "c")},
+			[]string{"call_1_0", "call_1_1", "call_1_2"},
+			[]string{"call_1_0", "call_1_1", "call_1_2"},
+		},
+		{
+			"mixed ids",
+			[]*genai.Part{functionCall("id1", "a"), functionCall("", "b")},
+			[]*genai.Part{functionResponse("id1", "a"), functionResponse("", "b")},
+			[]string{"id1", "call_1_1"},
+			[]string{"id1", "call_1_1"},
+		},
+		{
+			"extra response",
+			[]*genai.Part{functionCall("", "a")},
+			[]*genai.Part{functionResponse("", "a"), functionResponse("", "b")},
+			[]string{"call_1_0"},
+			[]string{"call_1_0", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contents := []*genai.Content{
+				genai.NewContentFromText("q", genai.RoleUser),
+				genai.NewContentFromParts(tt.calls, genai.RoleModel),
+				genai.NewContentFromParts(tt.responses, genai.RoleUser),
+			}
+			request, err := newChatRequest("model", contents, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(request.Messages) != 2+len(tt.responses) {
+				t.Fatalf("messages = %d, want %d", len(request.Messages), 2+len(tt.responses))
+			}
+
+			assistant := request.Messages[1]
+			callIDs := make([]string, len(assistant.ToolCalls))
+			for i, call := range assistant.ToolCalls {
+				callIDs[i] = call.ID
+			}
+			if !reflect.DeepEqual(callIDs, tt.callIDs) {
+				t.Errorf("call ids = %q, want %q", callIDs, tt.callIDs)
+			}
+
+			responseIDs := make([]string, len(tt.responses))
+			for i, message := range request.Messages[2:] {
+				if message.Role != roleTool {
+					t.Errorf("message role = %q, want %q", message.Role, roleTool)
+				}
+				responseIDs[i] = message.ToolCallID
+			}
+			if !reflect.DeepEqual(responseIDs, tt.responseIDs) {
+				t.Errorf("response ids = %q, want %q", responseIDs, tt.responseIDs)
+			}
+		})
+	}
+}
+
+func TestNewChatRequestMessages(t *testing.T) {
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText("be brief", genai.RoleUser),
+	}
+	contents := []*genai.Content{
+		genai.NewContentFromParts([]*genai.Part{
+			genai.NewPartFromText("describe"),
+			genai.NewPartFromBytes([]byte("abc"), "image/png"),
+		}, genai.RoleUser),
+		genai.NewContentFromParts([]*genai.Part{
+			{Text: "thinking", Thought: true},
+			genai.NewPartFromText("an image"),
+		}, genai.RoleModel),
+		genai.NewContentFromText("thanks", genai.RoleUser),
+	}
+	request, err := newChatRequest("model", contents, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*message{
+		{Role: roleSystem, Content: "be brief"},
+		{Role: roleUser, Content: []*contentPart{
+			{Type: "text", Text: "describe"},
+			{Type: "image_url", ImageURL: &imageURL{URL: "data:image/png;base64,YWJj"}},
+		}},
+		{Role: roleAssistant, Content: "an image"},
+		{Role: roleUser, Content: "thanks"},
+	}
+	if !reflect.DeepEqual(request.Messages, expected) {
+		t.Errorf("messages = %+v, want %+v", request.Messages, expected)
+	}
+
+	contents = []*genai.Content{genai.NewContentFromParts([]*genai.Part{
+		genai.NewPartFromBytes([]byte{0}, "audio/mp3"),
+	}, genai.RoleUser)}
+	if _, err := newChatRequest("model", contents, nil); err == nil {
+		t.Error("expected unsupported attachment type error")
+	}
+}
+
+func TestNewResponse(t *testing.T) {
+	response, err := newResponse(&chatResponse{
+		Choices: []*choice{
+			{Index: 0, Message: &responseDelta{Content: "answer"}, FinishReason: "stop"},
+			{Index: 1, Message: &responseDelta{Refusal: "refused"}, FinishReason: "content_filter"},
+			{Index: 2},
+			{Index: 3, Message: &responseDelta{ToolCalls: []*toolCall{
+				{ID: "id1", Function: functionCall{Name: "a", Arguments: `{"q": "x"}`}},
+				{ID: "id2", Function: functionCall{Name: "b", Arguments: " "}},
+			}}, FinishReason: "tool_calls"},
+			{Index: 4, Message: &responseDelta{Content: "trunc"}, FinishReason: "length"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*genai.Candidate{
+		{Index: 0, Content: genai.NewContentFromText("answer", genai.RoleModel),
+			FinishReason: genai.FinishReasonStop},
+		{Index: 1, Content: genai.NewContentFromText("refused", genai.RoleModel),
+			FinishReason: genai.FinishReasonSafety},
+		{Index: 3, Content: genai.NewContentFromParts([]*genai.Part{
+			{FunctionCall: &genai.FunctionCall{ID: "id1", Name: "a", Args: map[string]any{"q": "x"}}},
+			{FunctionCall: &genai.FunctionCall{ID: "id2", Name: "b"}},
+		}, genai.RoleModel), FinishReason: genai.FinishReasonStop},
+		{Index: 4, Content: genai.NewContentFromText("trunc", genai.RoleModel),
+			FinishReason: genai.FinishReasonMaxTokens},
+	}
+	if !reflect.DeepEqual(response.Candidates, expected) {
+		t.Errorf("candidates = %+v, want %+v", response.Candidates, expected)
+	}
+	if response.UsageMetadata != nil {
+		t.Errorf("unexpected usage metadata %+v", response.UsageMetadata)
+	}
+
+	_, err = newResponse(&chatResponse{Choices: []*choice{{Message: &responseDelta{ToolCalls: []*toolCall{
+		{Function: functionCall{Name: "a", Arguments: "{invalid"}},
+	}}}}})
+	if err == nil {
+		t.Error("expected error decoding the function call arguments")
+	}
+}
+
+func TestUsageMetadata(t *testing.T) {
+	u := &usage{PromptTokens: 10, CompletionTokens: 30, TotalTokens: 40}
+	expected := &genai.GenerateContentResponseUsageMetadata{
+		PromptTokenCount:     10,
+		CandidatesTokenCount: 30,
+		TotalTokenCount:      40,
+	}
+	if metadata := usageMetadata(u); !reflect.DeepEqual(metadata, expected) {
+		t.Errorf("usage metadata = %+v, want %+v", metadata, expected)
+	}
+
+	u.PromptTokensDetails = &struct {
+		CachedTokens int32 `json:"cached_tokens"`
+	}{CachedTokens: 4}
+	u.CompletionTokensDetails = &struct {
+		ReasoningTokens int32 `json:"reasoning_tokens"`
+	}{ReasoningTokens: 12}
+	// the reasoning tokens are included in the completion tokens
+	expected = &genai.GenerateContentResponseUsageMetadata{
+		PromptTokenCount:        10,
+		CachedContentTokenCount: 4,
+		CandidatesTokenCount:    18,
+		ThoughtsTokenCount:      12,
+		TotalTokenCount:         40,
+	}
+	if metadata := usageMetadata(u); !reflect.DeepEqual(metadata, expected) {
+		t.Errorf("usage metadata = %+v, want %+v", metadata, expected)
+	}
+
+	if metadata := usageMetadata(nil); metadata != nil {
+		t.Errorf("usage metadata = %+v, want nil", metadata)
+	}
+}
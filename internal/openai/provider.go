@@ -0,0 +1,300 @@
+// Package openai implements a generative model provider for the chat session,
+// backed by an OpenAI-compatible chat completion API, such as the OpenAI API
+// or a local Ollama server.
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/reugn/gemini-cli/gemini"
+	"google.golang.org/genai"
+)
+
+const (
+	// DefaultBaseURL is the base URL of the OpenAI API.
+	DefaultBaseURL = "https://api.openai.com/v1"
+	// OllamaBaseURL is the base URL of the OpenAI-compatible API of a local
+	// Ollama server.
+	OllamaBaseURL = "http://localhost:11434/v1"
+
+	// charsPerToken is the average number of characters per token, used to
+	// estimate the number of tokens.
+	charsPerToken = 4
+	// maxEventSize is the maximum size of a streamed server-sent event.
+	maxEventSize = 4 << 20
+
+	// The type URLs of the API error details.
+	errorInfoType = "type.googleapis.com/google.rpc.ErrorInfo"
+	retryInfoType = "type.googleapis.com/google.rpc.RetryInfo"
+)
+
+// Provider is a generative model provider backed by an OpenAI-compatible
+// chat completion API.
+type Provider struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+}
+
+var _ gemini.Provider = (*Provider)(nil)
+
+// NewProvider returns a new [Provider] for the API at the base URL.
+// The API key is optional, as local servers usually do not require one.
+func NewProvider(baseURL, apiKey string) *Provider {
+	return &Provider{
+		client:  http.DefaultClient,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+	}
+}
+
+// GenerateContent generates the model response to the contents.
+func (p *Provider) GenerateContent(ctx context.Context, model string, contents []*genai.Content,
+	config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	request, err := newChatRequest(model, contents, config)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.do(ctx, http.MethodPost, "/chat/completions", request)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	response := &chatResponse{}
+	if err := json.NewDecoder(body).Decode(response); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	return newResponse(response)
+}
+
+// GenerateContentStream is like GenerateContent, but the response is streamed
+// in chunks. The function calls are yielded once their arguments are complete.
+func (p *Provider) GenerateContentStream(ctx context.Context, model string, contents []*genai.Content,
+	config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		request, err := newChatRequest(model, contents, config)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		request.Stream = true
+		request.StreamOptions = &streamOptions{IncludeUsage: true}
+
+		body, err := p.do(ctx, http.MethodPost, "/chat/completions", request)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer body.Close()
+
+		stream := newStreamDecoder()
+		for event, err := range readEvents(body) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			chunk := &chatResponse{}
+			if err := json.Unmarshal(event, chunk); err != nil {
+				yield(nil, fmt.Errorf("error decoding response: %w", err))
+				return
+			}
+
+			response, err := stream.decode(chunk)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if response != nil && !yield(response, nil) {
+				return
+			}
+		}
+	}
+}
+
+// CountTokens returns the estimated number of tokens in the contents, as
+// the chat completion API does not provide token counting. The estimate is
+// based on the length of the text, and the function calls and responses.
+func (p *Provider) CountTokens(_ context.Context, _ string, contents []*genai.Content) (int32, error) {
+	var chars int
+	for _, content := range contents {
+		for _, part := range content.Parts {
+			chars += utf8.RuneCountInString(part.Text)
+			if part.FunctionCall != nil {
+				encoded, _ := json.Marshal(part.FunctionCall)
+				chars += len(encoded)
+			}
+			if part.FunctionResponse != nil {
+				encoded, _ := json.Marshal(part.FunctionResponse)
+				chars += len(encoded)
+			}
+		}
+	}
+
+	return int32((chars + charsPerToken - 1) / charsPerToken), nil //nolint:gosec // bounded by the request size
+}
+
+// ListModels returns the names of the available generative models.
+func (p *Provider) ListModels(ctx context.Context) ([]string, error) {
+	body, err := p.do(ctx, http.MethodGet, "/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	list := &modelList{}
+	if err := json.NewDecoder(body).Decode(list); err != nil {
+		return nil, fmt.Errorf("error decoding models: %w", err)
+	}
+
+	models := make([]string, len(list.Data))
+	for i, model := range list.Data {
+		models[i] = model.ID
+	}
+	slices.Sort(models)
+
+	return models, nil
+}
+
+// ModelInfo returns information about the generative model. The input token
+// limit is not provided by the chat completion API.
+func (p *Provider) ModelInfo(ctx context.Context, name string) (*genai.Model, error) {
+	body, err := p.do(ctx, http.MethodGet, "/models/"+url.PathEscape(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	info := &model{}
+	if err := json.NewDecoder(body).Decode(info); err != nil {
+		return nil, fmt.Errorf("error decoding model info: %w", err)
+	}
+
+	return &genai.Model{
+		Name:        info.ID,
+		DisplayName: info.ID,
+		Description: info.OwnedBy,
+	}, nil
+}
+
+// do sends the request with the JSON-encoded payload and returns the response
+// body. The unsuccessful responses are returned as [genai.APIError].
+func (p *Provider) do(ctx context.Context, method, path string, payload any) (io.ReadCloser, error) {
+	var body io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding request: %w", err)
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if p.apiKey != "" {
+		request.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		defer response.Body.Close()
+		return nil, newAPIError(response)
+	}
+
+	return response.Body, nil
+}
+
+// newAPIError returns the error described by the unsuccessful response.
+// The error code and the Retry-After header are reported as the error details,
+// in the format of the Gemini API.
+func newAPIError(response *http.Response) error {
+	apiErr := genai.APIError{
+		Code:    response.StatusCode,
+		Status:  http.StatusText(response.StatusCode),
+		Message: response.Status,
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(response.Body, maxEventSize))
+	errResponse := &errorResponse{}
+	if err := json.Unmarshal(data, errResponse); err == nil && errResponse.Error != nil {
+		var details struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+			Code    any    `json:"code"`
+		}
+		var message string
+		switch {
+		case json.Unmarshal(errResponse.Error, &details) == nil && details.Message != "":
+			apiErr.Message = details.Message
+			if reason, ok := details.Code.(string); ok && reason != "" {
+				apiErr.Details = append(apiErr.Details, errorInfo(reason))
+			} else if details.Type != "" {
+				apiErr.Details = append(apiErr.Details, errorInfo(details.Type))
+			}
+		case json.Unmarshal(errResponse.Error, &message) == nil && message != "":
+			apiErr.Message = message
+		}
+	}
+
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		apiErr.Details = append(apiErr.Details, map[string]any{
+			"@type":      retryInfoType,
+			"retryDelay": fmt.Sprintf("%ds", seconds),
+		})
+	}
+
+	return apiErr
+}
+
+// errorInfo returns the error details with the reason of the error.
+func errorInfo(reason string) map[string]any {
+	return map[string]any{"@type": errorInfoType, "reason": reason}
+}
+
+// readEvents returns the data of the server-sent events read from the reader,
+// until the stream termination event.
+func readEvents(r io.Reader) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxEventSize)
+		for scanner.Scan() {
+			data, ok := bytes.CutPrefix(scanner.Bytes(), []byte("data:"))
+			if !ok {
+				continue // skip comments and other fields
+			}
+			data = bytes.TrimSpace(data)
+			if string(data) == "[DONE]" {
+				return
+			}
+			if !yield(data, nil) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(nil, fmt.Errorf("error reading response: %w", err))
+		}
+	}
+}
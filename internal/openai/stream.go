@@ -0,0 +1,93 @@
+package openai
+
+import (
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// streamDecoder converts the streamed chat completion chunks. The text deltas
+// are converted as they arrive, while the tool call deltas are accumulated
+// and converted once the choice is finished.
+type streamDecoder struct {
+	// toolCalls contains the accumulated tool calls, keyed by the choice index.
+	toolCalls map[int32][]*toolCall
+	// arguments contains the accumulated tool call arguments.
+	arguments map[*toolCall]*strings.Builder
+}
+
+func newStreamDecoder() *streamDecoder {
+	return &streamDecoder{
+		toolCalls: make(map[int32][]*toolCall),
+		arguments: make(map[*toolCall]*strings.Builder),
+	}
+}
+
+// decode converts the chunk, returning nil if there is nothing to yield yet.
+func (d *streamDecoder) decode(chunk *chatResponse) (*genai.GenerateContentResponse, error) {
+	response := &genai.GenerateContentResponse{UsageMetadata: usageMetadata(chunk.Usage)}
+	for _, choice := range chunk.Choices {
+		candidate := &genai.Candidate{
+			Index:        choice.Index,
+			FinishReason: finishReason(choice.FinishReason),
+		}
+
+		var parts []*genai.Part
+		if delta := choice.Delta; delta != nil {
+			if text := delta.Content + delta.Refusal; text != "" {
+				parts = append(parts, genai.NewPartFromText(text))
+			}
+			d.accumulate(choice.Index, delta.ToolCalls)
+		}
+		if choice.FinishReason != "" {
+			for _, call := range d.toolCalls[choice.Index] {
+				call.Function.Arguments = d.arguments[call].String()
+				part, err := functionCallPart(call)
+				if err != nil {
+					return nil, err
+				}
+				parts = append(parts, part)
+			}
+			delete(d.toolCalls, choice.Index)
+		}
+
+		if len(parts) > 0 {
+			candidate.Content = &genai.Content{Role: genai.RoleModel, Parts: parts}
+		}
+		if candidate.Content != nil || choice.FinishReason != "" {
+			response.Candidates = append(response.Candidates, candidate)
+		}
+	}
+
+	if len(response.Candidates) == 0 && response.UsageMetadata == nil {
+		return nil, nil
+	}
+	return response, nil
+}
+
+// accumulate merges the tool call deltas of the choice, which are identified
+// by their index.
+func (d *streamDecoder) accumulate(choice int32, deltas []*toolCall) {
+	for _, delta := range deltas {
+		calls := d.toolCalls[choice]
+
+		var call *toolCall
+		if delta.Index != nil && *delta.Index < len(calls) {
+			call = calls[*delta.Index]
+		} else if delta.Index == nil && delta.ID == "" && len(calls) > 0 {
+			call = calls[len(calls)-1]
+		} else {
+			call = &toolCall{ID: delta.ID, Type: delta.Type}
+			d.toolCalls[choice] = append(calls, call)
+			d.arguments[call] = &strings.Builder{}
+		}
+
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		if delta.Function.Name != "" {
+			call.Function.Name = delta.Function.Name
+		}
+		d.arguments[call].WriteString(delta.Function.Arguments)
+	}
+}
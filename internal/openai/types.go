@@ -0,0 +1,127 @@
+package openai
+
+import "encoding/json"
+
+// The chat completion API message roles.
+const (
+	roleSystem    = "system"
+	roleUser      = "user"
+	roleAssistant = "assistant"
+	roleTool      = "tool"
+)
+
+type chatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []*message      `json:"messages"`
+	Tools          []*tool         `json:"tools,omitempty"`
+	Temperature    *float32        `json:"temperature,omitempty"`
+	TopP           *float32        `json:"top_p,omitempty"`
+	MaxTokens      int32           `json:"max_tokens,omitempty"`
+	N              int32           `json:"n,omitempty"`
+	Stop           []string        `json:"stop,omitempty"`
+	Seed           *int32          `json:"seed,omitempty"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+	Stream         bool            `json:"stream,omitempty"`
+	StreamOptions  *streamOptions  `json:"stream_options,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type responseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *jsonSchema `json:"json_schema,omitempty"`
+}
+
+type jsonSchema struct {
+	Name   string `json:"name"`
+	Schema any    `json:"schema"`
+}
+
+type message struct {
+	Role string `json:"role"`
+	// Content is either a string or a list of content parts.
+	Content    any         `json:"content,omitempty"`
+	ToolCalls  []*toolCall `json:"tool_calls,omitempty"`
+	ToolCallID string      `json:"tool_call_id,omitempty"`
+}
+
+type contentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+type tool struct {
+	Type     string       `json:"type"`
+	Function *functionDef `json:"function"`
+}
+
+type functionDef struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters"`
+}
+
+type toolCall struct {
+	// Index identifies the tool call in the streamed deltas.
+	Index    *int         `json:"index,omitempty"`
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Function functionCall `json:"function"`
+}
+
+type functionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+type chatResponse struct {
+	Choices []*choice `json:"choices"`
+	Usage   *usage    `json:"usage,omitempty"`
+}
+
+type choice struct {
+	Index        int32          `json:"index"`
+	Message      *responseDelta `json:"message,omitempty"`
+	Delta        *responseDelta `json:"delta,omitempty"`
+	FinishReason string         `json:"finish_reason,omitempty"`
+}
+
+type responseDelta struct {
+	Content   string      `json:"content,omitempty"`
+	Refusal   string      `json:"refusal,omitempty"`
+	ToolCalls []*toolCall `json:"tool_calls,omitempty"`
+}
+
+type usage struct {
+	PromptTokens        int32 `json:"prompt_tokens"`
+	CompletionTokens    int32 `json:"completion_tokens"`
+	TotalTokens         int32 `json:"total_tokens"`
+	PromptTokensDetails *struct {
+		CachedTokens int32 `json:"cached_tokens"`
+	} `json:"prompt_tokens_details,omitempty"`
+	CompletionTokensDetails *struct {
+		ReasoningTokens int32 `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details,omitempty"`
+}
+
+type modelList struct {
+	Data []*model `json:"data"`
+}
+
+type model struct {
+	ID      string `json:"id"`
+	OwnedBy string `json:"owned_by,omitempty"`
+}
+
+// errorResponse represents an error response body. The error is either
+// an object with a message, or a plain string.
+type errorResponse struct {
+	Error json.RawMessage `json:"error"`
+}
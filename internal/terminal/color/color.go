@@ -4,6 +4,7 @@ import "fmt"
 
 const (
 	reset   = "\033[0m"
+	dim     = "\033[2m"
 	red     = "\033[31m"
 	green   = "\033[32m"
 	yellow  = "\033[33m"
@@ -53,3 +54,8 @@ func Gray(str string) string {
 func White(str string) string {
 	return fmt.Sprintf("%s%s%s", white, str, reset)
 }
+
+// Dim adds faint intensity to str in terminal.
+func Dim(str string) string {
+	return fmt.Sprintf("%s%s%s", dim, str, reset)
+}
@@ -4,7 +4,9 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"math"
 	"time"
+	"unicode/utf8"
 )
 
 const (
@@ -14,32 +16,53 @@ const (
 )
 
 // Spinner is a visual indicator of progress displayed in the terminal as a
-// scrolling dot animation.
+// scrolling dot animation, or as a countdown. Starting a running spinner or
+// stopping a stopped one has no effect.
 type Spinner struct {
-	writer   *bufio.Writer
-	interval time.Duration
-	signal   chan struct{}
+	writer    *bufio.Writer
+	interval  time.Duration
+	signal    chan struct{}
+	countdown chan countdown
+	running   bool
 
 	maxLength int
 	length    int
 }
 
+// countdown represents a message displayed with the remaining time until
+// the deadline.
+type countdown struct {
+	message  string
+	deadline time.Time
+}
+
+// remaining returns the remaining whole seconds until the deadline, rounded up.
+func (c *countdown) remaining() int {
+	return int(math.Ceil(time.Until(c.deadline).Seconds()))
+}
+
 // NewSpinner returns a new Spinner.
 func NewSpinner(w io.Writer, interval time.Duration, length int) *Spinner {
 	return &Spinner{
 		writer:    bufio.NewWriter(w),
 		interval:  interval,
 		signal:    make(chan struct{}),
+		countdown: make(chan countdown, 1),
 		maxLength: length,
 	}
 }
 
 //nolint:errcheck
 func (s *Spinner) Start() {
+	if s.running {
+		return
+	}
+	s.running = true
 	go func() {
 		ticker := time.NewTicker(s.interval)
 		defer ticker.Stop()
 		s.length = 0
+		var active *countdown
 		for {
 			select {
 			case <-s.signal:
@@ -48,12 +71,22 @@ func (s *Spinner) Start() {
 				}
 				s.signal <- struct{}{}
 				return
+			case c := <-s.countdown:
+				active = &c
+				s.drawCountdown(active)
 			case <-ticker.C:
-				if s.length < s.maxLength {
+				switch {
+				case active != nil && active.remaining() > 0:
+					s.drawCountdown(active)
+				case active != nil:
+					active = nil
+					s.Clear()
+					s.length = 0
+				case s.length < s.maxLength:
 					s.writer.WriteRune(progressRune)
 					s.writer.Flush()
 					s.length++
-				} else {
+				default:
 					s.Clear()
 					s.length = 0
 				}
@@ -62,6 +95,33 @@ func (s *Spinner) Start() {
 	}()
 }
 
+// Countdown displays the message followed by the remaining seconds until
+// the deadline, after which the dot animation resumes. It has no effect if
+// the spinner is not running.
+func (s *Spinner) Countdown(message string, deadline time.Time) {
+	if !s.running {
+		return
+	}
+	select {
+	case <-s.countdown: // drop the pending countdown
+	default:
+	}
+	s.countdown <- countdown{message: message, deadline: deadline}
+}
+
+// drawCountdown replaces the displayed progress with the countdown.
+//
+//nolint:errcheck
+func (s *Spinner) drawCountdown(c *countdown) {
+	if s.length > 0 {
+		s.Clear()
+	}
+	text := fmt.Sprintf("%s %ds", c.message, max(c.remaining(), 0))
+	s.writer.WriteString(text)
+	s.writer.Flush()
+	s.length = utf8.RuneCountInString(text)
+}
+
 //nolint:errcheck,staticcheck
 func (s *Spinner) Clear() {
 	s.writer.WriteString(fmt.Sprintf(moveCursorBackward, s.length))
@@ -70,6 +130,10 @@ func (s *Spinner) Clear() {
 }
 
 func (s *Spinner) Stop() {
+	if !s.running {
+		return
+	}
+	s.running = false
 	s.signal <- struct{}{}
 	<-s.signal
 }